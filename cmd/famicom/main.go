@@ -1,15 +1,23 @@
 package main
 
 import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"flag"
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/tabo-syu/famicom/internal/asm"
 	"github.com/tabo-syu/famicom/internal/bus"
 	"github.com/tabo-syu/famicom/internal/cpu"
 	"github.com/tabo-syu/famicom/internal/game"
 	"github.com/tabo-syu/famicom/internal/memory"
+	"github.com/tabo-syu/famicom/internal/ppu"
 	"github.com/tabo-syu/famicom/internal/rom"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -29,29 +37,10 @@ func main() {
 	os.Exit(success)
 }
 
-var code = []byte{
-	0x20, 0x06, 0x06, 0x20, 0x38, 0x06, 0x20, 0x0d, 0x06, 0x20, 0x2a, 0x06, 0x60, 0xa9, 0x02,
-	0x85, 0x02, 0xa9, 0x04, 0x85, 0x03, 0xa9, 0x11, 0x85, 0x10, 0xa9, 0x10, 0x85, 0x12, 0xa9,
-	0x0f, 0x85, 0x14, 0xa9, 0x04, 0x85, 0x11, 0x85, 0x13, 0x85, 0x15, 0x60, 0xa5, 0xfe, 0x85,
-	0x00, 0xa5, 0xfe, 0x29, 0x03, 0x18, 0x69, 0x02, 0x85, 0x01, 0x60, 0x20, 0x4d, 0x06, 0x20,
-	0x8d, 0x06, 0x20, 0xc3, 0x06, 0x20, 0x19, 0x07, 0x20, 0x20, 0x07, 0x20, 0x2d, 0x07, 0x4c,
-	0x38, 0x06, 0xa5, 0xff, 0xc9, 0x77, 0xf0, 0x0d, 0xc9, 0x64, 0xf0, 0x14, 0xc9, 0x73, 0xf0,
-	0x1b, 0xc9, 0x61, 0xf0, 0x22, 0x60, 0xa9, 0x04, 0x24, 0x02, 0xd0, 0x26, 0xa9, 0x01, 0x85,
-	0x02, 0x60, 0xa9, 0x08, 0x24, 0x02, 0xd0, 0x1b, 0xa9, 0x02, 0x85, 0x02, 0x60, 0xa9, 0x01,
-	0x24, 0x02, 0xd0, 0x10, 0xa9, 0x04, 0x85, 0x02, 0x60, 0xa9, 0x02, 0x24, 0x02, 0xd0, 0x05,
-	0xa9, 0x08, 0x85, 0x02, 0x60, 0x60, 0x20, 0x94, 0x06, 0x20, 0xa8, 0x06, 0x60, 0xa5, 0x00,
-	0xc5, 0x10, 0xd0, 0x0d, 0xa5, 0x01, 0xc5, 0x11, 0xd0, 0x07, 0xe6, 0x03, 0xe6, 0x03, 0x20,
-	0x2a, 0x06, 0x60, 0xa2, 0x02, 0xb5, 0x10, 0xc5, 0x10, 0xd0, 0x06, 0xb5, 0x11, 0xc5, 0x11,
-	0xf0, 0x09, 0xe8, 0xe8, 0xe4, 0x03, 0xf0, 0x06, 0x4c, 0xaa, 0x06, 0x4c, 0x35, 0x07, 0x60,
-	0xa6, 0x03, 0xca, 0x8a, 0xb5, 0x10, 0x95, 0x12, 0xca, 0x10, 0xf9, 0xa5, 0x02, 0x4a, 0xb0,
-	0x09, 0x4a, 0xb0, 0x19, 0x4a, 0xb0, 0x1f, 0x4a, 0xb0, 0x2f, 0xa5, 0x10, 0x38, 0xe9, 0x20,
-	0x85, 0x10, 0x90, 0x01, 0x60, 0xc6, 0x11, 0xa9, 0x01, 0xc5, 0x11, 0xf0, 0x28, 0x60, 0xe6,
-	0x10, 0xa9, 0x1f, 0x24, 0x10, 0xf0, 0x1f, 0x60, 0xa5, 0x10, 0x18, 0x69, 0x20, 0x85, 0x10,
-	0xb0, 0x01, 0x60, 0xe6, 0x11, 0xa9, 0x06, 0xc5, 0x11, 0xf0, 0x0c, 0x60, 0xc6, 0x10, 0xa5,
-	0x10, 0x29, 0x1f, 0xc9, 0x1f, 0xf0, 0x01, 0x60, 0x4c, 0x35, 0x07, 0xa0, 0x00, 0xa5, 0xfe,
-	0x91, 0x00, 0x60, 0xa6, 0x03, 0xa9, 0x00, 0x81, 0x10, 0xa2, 0x00, 0xa9, 0x01, 0x81, 0x10,
-	0x60, 0xa6, 0xff, 0xea, 0xea, 0xca, 0xd0, 0xfb, 0x60,
-}
+// snakeSource はスネークのソース。起動するたびにアセンブルする。
+//
+//go:embed snake.asm
+var snakeSource string
 
 // programStart はスネークのプログラムを展開する RAM 上のアドレス。
 const programStart = 0x06_00
@@ -75,27 +64,172 @@ func newSnakeROM() []byte {
 }
 
 func run() error {
+	if len(os.Args) > 1 && os.Args[1] == "asm" {
+		return runAsm(os.Args[2:])
+	}
+
+	flags := flag.NewFlagSet("famicom", flag.ContinueOnError)
+	palettePath := flags.String("palette", "", "path to a .pal file (64 or 512 colors)")
+	tracePath := flags.String("trace", "", "write a nestest.log style CPU trace to this file")
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		return err
+	}
+
+	if flags.NArg() < 1 {
+		return errors.New("usage: famicom [-palette file.pal] [-trace file.log] <rom.nes> | famicom snake | famicom asm [-ines] [-o out] <source.asm>")
+	}
+
+	switch flags.Arg(0) {
+	case "snake":
+		return runSnake()
+	default:
+		palette, err := loadPalette(*palettePath)
+		if err != nil {
+			return err
+		}
+
+		return runROM(flags.Arg(0), palette, *tracePath)
+	}
+}
+
+// loadPalette は path の .pal ファイルを読み込む。path が空なら既定のパレットを返す。
+func loadPalette(path string) (ppu.Palette, error) {
+	if path == "" {
+		return ppu.DefaultPalette, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return ppu.Palette{}, err
+	}
+	defer file.Close()
+
+	palette, err := ppu.LoadPalette(file)
+	if err != nil {
+		return ppu.Palette{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return palette, nil
+}
+
+// runROM は path の iNES ファイルを読み込み、リセットベクタからカートリッジを実行する。
+// tracePath が空でなければ、実行した命令のトレースをそのファイルに書き出す。
+func runROM(path string, palette ppu.Palette, tracePath string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	rom, err := rom.NewROM(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	memory := memory.NewMemory()
+	bus, err := bus.NewBus(&memory, rom)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	cpu := cpu.NewCPU(bus)
+	cpu.Reset(0xFF_FC)
+
+	if tracePath != "" {
+		file, err := os.Create(tracePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		trace := bufio.NewWriter(file)
+		defer trace.Flush()
+		cpu.Tracer = trace
+	}
+
+	g := game.NewNES(&cpu, bus.PPU, palette)
+	ebiten.SetWindowSize(ppu.Width*2, ppu.Height*2)
+	ebiten.SetWindowTitle(filepath.Base(path))
+
+	return ebiten.RunGame(g)
+}
+
+// runSnake は組み込みのスネークを起動する。
+func runSnake() error {
+	snake, err := asm.Assemble(snakeSource)
+	if err != nil {
+		return fmt.Errorf("snake.asm: %w", err)
+	}
+	// CPU.Load は常に programStart に展開するため、.org がずれているとリセットベクタと食い違う
+	if snake.Origin != programStart {
+		return fmt.Errorf("snake.asm: program starts at $%04X, want $%04X", snake.Origin, programStart)
+	}
+
 	memory := memory.NewMemory()
 	rom, err := rom.NewROM(newSnakeROM())
 	if err != nil {
 		return err
 	}
 
-	cpu := cpu.NewCPU(bus.NewBus(&memory, rom))
-	cpu.Load(code)
-	cpu.Reset(0xFF_FC)
+	b, err := bus.NewBus(&memory, rom)
+	if err != nil {
+		return err
+	}
+	c := cpu.NewCPU(b)
+	// スネークはファミコン向けではない汎用の 6502 のプログラム
+	c.Variant = cpu.NMOS6502
+	// スネークはゲームオーバーで BRK に到達して止まる
+	c.HaltOnBRK = true
+	// スネークの速さは CPU の速さで決まるため、遊べる速さまで落とす
+	c.ClockRate = 50_000
+	c.Load(snake.Code)
+	c.Reset(0xFF_FC)
 
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	cpu.Bus.WriteMemory(0xFE, byte(rng.Intn(15)+1))
+	c.Bus.WriteMemory(0xFE, byte(rng.Intn(15)+1))
 
-	g := game.NewGame(&cpu, rng)
+	g := game.NewGame(&c, rng)
 	ebiten.SetWindowSize(game.ScreenSize, game.ScreenSize)
 	ebiten.SetWindowTitle("Snake Game")
 
-	go cpu.Run()
-	if err := ebiten.RunGame(g); err != nil {
+	return ebiten.RunGame(g)
+}
+
+// runAsm はアセンブリのソースをアセンブルし、CPU.Load で読み込める生のバイナリか iNES イメージを書き出す。
+// 出力先を省略すると、ソースの拡張子を .bin (iNES なら .nes) に変えたファイルに書き出す。
+func runAsm(args []string) error {
+	flags := flag.NewFlagSet("famicom asm", flag.ContinueOnError)
+	ines := flags.Bool("ines", false, "write an iNES image (NROM) instead of a raw binary")
+	outPath := flags.String("o", "", "output file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: famicom asm [-ines] [-o out] <source.asm>")
+	}
+	path := flags.Arg(0)
+
+	source, err := os.ReadFile(path)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	program, err := asm.Assemble(string(source))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	out, ext := program.Code, ".bin"
+	if *ines {
+		out, err = program.INES()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		ext = ".nes"
+	}
+
+	if *outPath == "" {
+		*outPath = strings.TrimSuffix(path, filepath.Ext(path)) + ext
+	}
+
+	return os.WriteFile(*outPath, out, 0o644)
 }
@@ -0,0 +1,258 @@
+; スネーク
+;
+; easy6502 のスネークを移植したもの。W A S D で向きを変える。
+; 画面は 0x0200-0x05FF の 32x32 ドットで、1 バイトが 1 ドットの色になる。
+; https://skilldrick.github.io/easy6502/#snake
+
+appleL         = $00 ; りんごの画面上のアドレス (下位)
+appleH         = $01 ; りんごの画面上のアドレス (上位)
+snakeDirection = $02 ; 進む向き (下の moving* のいずれか)
+snakeLength    = $03 ; 長さ (バイト数なので節の数の 2 倍)
+snakeHeadL     = $10 ; 頭の画面上のアドレス (下位)
+snakeHeadH     = $11 ; 頭の画面上のアドレス (上位)
+snakeBodyStart = $12 ; ここから胴体の節のアドレスが 2 バイトずつ並ぶ
+
+; 向きはそれぞれ別のビットで表す
+movingUp    = 1
+movingRight = 2
+movingDown  = 4
+movingLeft  = 8
+
+; 向きを変えるキーの ASCII コード
+ASCII_w = $77
+ASCII_a = $61
+ASCII_s = $73
+ASCII_d = $64
+
+; 乱数と最後に押したキーは game パッケージが書き込む
+sysRandom  = $FE
+sysLastKey = $FF
+
+        .org $0600
+
+        jsr init
+        jsr loop
+
+init:
+        jsr initSnake
+        jsr generateApplePosition
+        rts
+
+initSnake:
+        lda #movingRight        ; 最初の向き
+        sta snakeDirection
+
+        lda #4                  ; 最初の長さ (2 節)
+        sta snakeLength
+
+        lda #$11
+        sta snakeHeadL
+
+        lda #$10
+        sta snakeBodyStart
+
+        lda #$0F
+        sta snakeBodyStart+2    ; 胴体の 1 節目
+
+        lda #$04
+        sta snakeHeadH
+        sta snakeBodyStart+1    ; 胴体の 1 節目
+        sta snakeBodyStart+3    ; 胴体の 2 節目
+        rts
+
+generateApplePosition:
+        lda sysRandom           ; 下位はランダムな 1 バイト
+        sta appleL
+
+        lda sysRandom           ; 上位は 2 から 5 のどれか
+        and #$03
+        clc
+        adc #2
+        sta appleH
+        rts
+
+loop:
+        jsr readKeys
+        jsr checkCollision
+        jsr updateSnake
+        jsr drawApple
+        jsr drawSnake
+        jsr spinWheels
+        jmp loop
+
+readKeys:
+        lda sysLastKey
+        cmp #ASCII_w
+        beq upKey
+        cmp #ASCII_d
+        beq rightKey
+        cmp #ASCII_s
+        beq downKey
+        cmp #ASCII_a
+        beq leftKey
+        rts
+upKey:
+        lda #movingDown         ; 逆向きには曲がれない
+        bit snakeDirection
+        bne illegalMove
+
+        lda #movingUp
+        sta snakeDirection
+        rts
+rightKey:
+        lda #movingLeft
+        bit snakeDirection
+        bne illegalMove
+
+        lda #movingRight
+        sta snakeDirection
+        rts
+downKey:
+        lda #movingUp
+        bit snakeDirection
+        bne illegalMove
+
+        lda #movingDown
+        sta snakeDirection
+        rts
+leftKey:
+        lda #movingRight
+        bit snakeDirection
+        bne illegalMove
+
+        lda #movingLeft
+        sta snakeDirection
+        rts
+illegalMove:
+        rts
+
+checkCollision:
+        jsr checkAppleCollision
+        jsr checkSnakeCollision
+        rts
+
+checkAppleCollision:
+        lda appleL
+        cmp snakeHeadL
+        bne doneCheckingAppleCollision
+        lda appleH
+        cmp snakeHeadH
+        bne doneCheckingAppleCollision
+
+        inc snakeLength         ; りんごを食べたら 1 節伸びる
+        inc snakeLength
+        jsr generateApplePosition
+doneCheckingAppleCollision:
+        rts
+
+checkSnakeCollision:
+        ldx #2                  ; 胴体の 1 節目から調べる
+snakeCollisionLoop:
+        lda snakeHeadL,x
+        cmp snakeHeadL
+        bne continueCollisionLoop
+
+        lda snakeHeadH,x
+        cmp snakeHeadH
+        beq didCollide
+
+continueCollisionLoop:
+        inx
+        inx
+        cpx snakeLength         ; 最後の節までぶつかっていない
+        beq didntCollide
+        jmp snakeCollisionLoop
+
+didCollide:
+        jmp gameOver
+didntCollide:
+        rts
+
+updateSnake:
+        ldx snakeLength         ; 胴体を後ろから 1 節ずつずらす
+        dex
+        txa
+updateLoop:
+        lda snakeHeadL,x
+        sta snakeBodyStart,x
+        dex
+        bpl updateLoop
+
+        lda snakeDirection
+        lsr
+        bcs up
+        lsr
+        bcs right
+        lsr
+        bcs down
+        lsr
+        bcs left
+up:
+        lda snakeHeadL
+        sec
+        sbc #$20
+        sta snakeHeadL
+        bcc upUp
+        rts
+upUp:
+        dec snakeHeadH
+        lda #$01                ; 画面の上端を越えた
+        cmp snakeHeadH
+        beq collision
+        rts
+right:
+        inc snakeHeadL
+        lda #$1F                ; 画面の右端を越えた
+        bit snakeHeadL
+        beq collision
+        rts
+down:
+        lda snakeHeadL
+        clc
+        adc #$20
+        sta snakeHeadL
+        bcs downDown
+        rts
+downDown:
+        inc snakeHeadH
+        lda #$06                ; 画面の下端を越えた
+        cmp snakeHeadH
+        beq collision
+        rts
+left:
+        dec snakeHeadL
+        lda snakeHeadL
+        and #$1F                ; 画面の左端を越えた
+        cmp #$1F
+        beq collision
+        rts
+collision:
+        jmp gameOver
+
+drawApple:
+        ldy #0
+        lda sysRandom
+        sta (appleL),y
+        rts
+
+drawSnake:
+        ldx snakeLength         ; 尻尾を消す
+        lda #0
+        sta (snakeHeadL,x)
+
+        ldx #0                  ; 頭を描く
+        lda #1
+        sta (snakeHeadL,x)
+        rts
+
+spinWheels:
+        ldx sysLastKey          ; 速さの調整のための空ループ
+spinLoop:
+        nop
+        nop
+        dex
+        bne spinLoop
+        rts
+
+gameOver:
+        brk                     ; CPU の HaltOnBRK で止まる
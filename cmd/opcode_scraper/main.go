@@ -71,6 +71,11 @@ type mode struct {
 	Cycles string
 }
 
+// PageCrossCycle はページをまたぐと 1 サイクル増えるかどうかを返す。
+func (m mode) PageCrossCycle() bool {
+	return strings.Contains(m.Cycles, "+1 if page crossed")
+}
+
 type opcode struct {
 	Name   string
 	Status status
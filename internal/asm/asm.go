@@ -0,0 +1,451 @@
+package asm
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tabo-syu/famicom/internal/cpu"
+)
+
+// Program はアセンブルした結果。
+type Program struct {
+	// Origin は Code の先頭を置くアドレス。
+	Origin uint16
+	// Code は Origin から最後に出力したバイトまでの内容。.org で飛ばした隙間は 0 で埋める。
+	Code []byte
+	// Symbols はラベルと定数の値。
+	Symbols map[string]int
+}
+
+// Assemble は 6502 のアセンブリを機械語にする。1 行の書き方は次のとおり。
+//
+//	; コメント
+//	speed = $10            ; 定数
+//	        .org $0600     ; 以降を置くアドレス
+//	loop:   lda #<table    ; ラベルと命令
+//	        sta (speed),y
+//	        bne loop
+//	table:  .byte 1, 2, "abc"
+//	        .word loop, table+2
+//
+// ニーモニックとディレクティブは大文字小文字を区別しないが、シンボルは区別する。
+// ゼロページに収まる値が 1 パス目で分かるオペランドはゼロページのモードを選ぶ。
+func Assemble(source string) (*Program, error) {
+	a := &assembler{symbols: map[string]int{}}
+
+	for pass := 1; pass <= 2; pass++ {
+		a.pass = pass
+		a.programCounter = 0
+		a.written = [0x1_00_00]bool{}
+
+		for i, line := range strings.Split(source, "\n") {
+			a.line = i + 1
+			if err := a.assembleLine(line); err != nil {
+				return nil, fmt.Errorf("line %d: %w", a.line, err)
+			}
+		}
+	}
+
+	start, end := -1, -1
+	for address, written := range a.written {
+		if written {
+			if start < 0 {
+				start = address
+			}
+			end = address
+		}
+	}
+	if start < 0 {
+		return &Program{Symbols: a.symbols}, nil
+	}
+
+	return &Program{
+		Origin:  uint16(start),
+		Code:    append([]byte(nil), a.memory[start:end+1]...),
+		Symbols: a.symbols,
+	}, nil
+}
+
+type assembler struct {
+	pass           int
+	line           int
+	programCounter int
+	// address は処理中の行の先頭のアドレス。式の * はこの値になる。
+	address int
+	symbols map[string]int
+	// modes は 1 パス目で選んだアドレッシングモード。
+	// 2 パス目で命令の長さが変わるとラベルがずれるため、同じモードを使う。
+	modes   map[int]cpu.AddressingMode
+	memory  [0x1_00_00]byte
+	written [0x1_00_00]bool
+}
+
+func (a *assembler) assembleLine(line string) error {
+	line = strings.TrimSpace(stripComment(line))
+	a.address = a.programCounter
+
+	// ラベル
+	if name := identifier(line); name != "" && strings.HasPrefix(line[len(name):], ":") {
+		if err := a.define(name, a.programCounter); err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line[len(name)+1:])
+	}
+	if line == "" {
+		return nil
+	}
+
+	// 定数
+	if name := identifier(line); name != "" && strings.HasPrefix(strings.TrimSpace(line[len(name):]), "=") {
+		expression := strings.TrimSpace(line[len(name):])[1:]
+		value, err := a.evaluate(expression)
+		if err != nil {
+			return err
+		}
+
+		return a.define(name, value)
+	}
+
+	word, operand := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		word, operand = line[:i], strings.TrimSpace(line[i:])
+	}
+
+	if strings.HasPrefix(word, ".") {
+		return a.directive(strings.ToLower(word), operand)
+	}
+
+	return a.instruction(strings.ToUpper(word), operand)
+}
+
+// define はシンボルを定義する。2 パス目は 1 パス目と同じ値になるはずなので上書きする。
+func (a *assembler) define(name string, value int) error {
+	if _, ok := a.symbols[name]; ok && a.pass == 1 {
+		return fmt.Errorf("symbol %q is already defined", name)
+	}
+	a.symbols[name] = value
+
+	return nil
+}
+
+func (a *assembler) directive(name string, operand string) error {
+	switch name {
+	case ".org":
+		value, err := a.evaluate(operand)
+		if err != nil {
+			return err
+		}
+		if value < 0 || value > 0xFF_FF {
+			return fmt.Errorf(".org $%X is out of range", value)
+		}
+		a.programCounter = value
+
+		return nil
+
+	case ".byte", ".word":
+		arguments, err := splitArguments(operand)
+		if err != nil {
+			return err
+		}
+		if len(arguments) == 0 {
+			return fmt.Errorf("%s needs at least one value", name)
+		}
+
+		for _, argument := range arguments {
+			if name == ".byte" && len(argument) >= 2 && strings.HasPrefix(argument, `"`) && strings.HasSuffix(argument, `"`) {
+				if err := a.emit([]byte(argument[1 : len(argument)-1])...); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			value, err := a.value(argument)
+			if err != nil {
+				return err
+			}
+
+			if name == ".byte" {
+				err = a.emitByte(value)
+			} else {
+				err = a.emitWord(value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	default:
+		return fmt.Errorf("unknown directive %s", name)
+	}
+}
+
+var (
+	indirectXOperand = regexp.MustCompile(`^\((.*),\s*[xX]\s*\)$`)
+	indirectYOperand = regexp.MustCompile(`^\((.*)\)\s*,\s*[yY]$`)
+	indirectOperand  = regexp.MustCompile(`^\((.*)\)$`)
+	indexedOperand   = regexp.MustCompile(`^(.*),\s*([xXyY])$`)
+)
+
+func (a *assembler) instruction(mnemonic string, operand string) error {
+	modes, ok := opcodes[mnemonic]
+	if !ok {
+		return fmt.Errorf("unknown instruction %s", mnemonic)
+	}
+
+	mode, expression, err := a.addressingMode(mnemonic, modes, operand)
+	if err != nil {
+		return err
+	}
+
+	if err := a.emit(modes[mode]); err != nil {
+		return err
+	}
+
+	switch mode {
+	case cpu.ImpliedMode, cpu.AccumulatorMode:
+		return nil
+
+	case cpu.RelativeMode:
+		target, err := a.value(expression)
+		if err != nil {
+			return err
+		}
+
+		offset := target - (a.programCounter + 1)
+		if a.pass == 2 && (offset < -128 || offset > 127) {
+			return fmt.Errorf("branch target $%04X is out of range", target)
+		}
+
+		return a.emit(byte(offset))
+
+	case cpu.ImmediateMode:
+		value, err := a.value(expression)
+		if err != nil {
+			return err
+		}
+
+		return a.emitByte(value)
+
+	case cpu.ZeroPageMode, cpu.ZeroPageXMode, cpu.ZeroPageYMode, cpu.IndirectXMode, cpu.IndirectYMode:
+		value, err := a.value(expression)
+		if err != nil {
+			return err
+		}
+		if a.pass == 2 && (value < 0 || value > 0xFF) {
+			return fmt.Errorf("zero page address $%X is out of range", value)
+		}
+
+		return a.emit(byte(value))
+
+	default:
+		value, err := a.value(expression)
+		if err != nil {
+			return err
+		}
+
+		return a.emitWord(value)
+	}
+}
+
+// addressingMode はオペランドの書き方からアドレッシングモードを選び、オペランドの式を返す。
+func (a *assembler) addressingMode(mnemonic string, modes map[cpu.AddressingMode]byte, operand string) (cpu.AddressingMode, string, error) {
+	has := func(mode cpu.AddressingMode) bool {
+		_, ok := modes[mode]
+
+		return ok
+	}
+	choose := func(mode cpu.AddressingMode, expression string) (cpu.AddressingMode, string, error) {
+		if !has(mode) {
+			return 0, "", fmt.Errorf("%s does not support this addressing mode", mnemonic)
+		}
+
+		return mode, expression, nil
+	}
+
+	switch {
+	case operand == "":
+		if has(cpu.ImpliedMode) {
+			return cpu.ImpliedMode, "", nil
+		}
+
+		return choose(cpu.AccumulatorMode, "")
+
+	case strings.EqualFold(operand, "A") && has(cpu.AccumulatorMode):
+		return cpu.AccumulatorMode, "", nil
+
+	case strings.HasPrefix(operand, "#"):
+		return choose(cpu.ImmediateMode, operand[1:])
+
+	case has(cpu.RelativeMode):
+		return cpu.RelativeMode, operand, nil
+	}
+
+	if match := indirectXOperand.FindStringSubmatch(operand); match != nil {
+		return choose(cpu.IndirectXMode, match[1])
+	}
+	if match := indirectYOperand.FindStringSubmatch(operand); match != nil {
+		return choose(cpu.IndirectYMode, match[1])
+	}
+	// LDA (base) のような括弧で囲んだ式と区別するため、間接モードを持つ命令でだけ括弧を間接参照とみなす
+	if match := indirectOperand.FindStringSubmatch(operand); match != nil && has(cpu.IndirectMode) {
+		return cpu.IndirectMode, match[1], nil
+	}
+
+	zeroPage, absolute := cpu.ZeroPageMode, cpu.AbsoluteMode
+	expression := operand
+	if match := indexedOperand.FindStringSubmatch(operand); match != nil {
+		expression = match[1]
+		if strings.EqualFold(match[2], "X") {
+			zeroPage, absolute = cpu.ZeroPageXMode, cpu.AbsoluteXMode
+		} else {
+			zeroPage, absolute = cpu.ZeroPageYMode, cpu.AbsoluteYMode
+		}
+	}
+
+	if a.pass == 2 {
+		return a.modes[a.line], expression, nil
+	}
+
+	mode := absolute
+	switch {
+	case !has(absolute):
+		mode = zeroPage
+	case has(zeroPage):
+		// 前方参照のラベルは値が分からないので絶対アドレスにする
+		if value, err := a.evaluate(expression); err == nil && 0 <= value && value <= 0xFF {
+			mode = zeroPage
+		}
+	}
+	if a.modes == nil {
+		a.modes = map[int]cpu.AddressingMode{}
+	}
+	a.modes[a.line] = mode
+
+	return choose(mode, expression)
+}
+
+// value は式を評価する。1 パス目では未定義のシンボルを 0 として扱う。
+func (a *assembler) value(expression string) (int, error) {
+	value, err := a.evaluate(expression)
+	if errors.Is(err, errUndefined) && a.pass == 1 {
+		return 0, nil
+	}
+
+	return value, err
+}
+
+func (a *assembler) emitByte(value int) error {
+	if a.pass == 2 && (value < -0x80 || value > 0xFF) {
+		return fmt.Errorf("value $%X does not fit in a byte", value)
+	}
+
+	return a.emit(byte(value))
+}
+
+func (a *assembler) emitWord(value int) error {
+	if a.pass == 2 && (value < -0x80_00 || value > 0xFF_FF) {
+		return fmt.Errorf("value $%X does not fit in a word", value)
+	}
+
+	return a.emit(byte(value), byte(value>>8))
+}
+
+func (a *assembler) emit(bytes ...byte) error {
+	for _, b := range bytes {
+		if a.programCounter > 0xFF_FF {
+			return errors.New("program counter overflows $FFFF")
+		}
+
+		if a.written[a.programCounter] {
+			return fmt.Errorf("$%04X is already written", a.programCounter)
+		}
+		a.written[a.programCounter] = true
+		a.memory[a.programCounter] = b
+		a.programCounter++
+	}
+
+	return nil
+}
+
+// stripComment は文字列や文字の中にない ; から行末までを取り除く。
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ';':
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+// splitArguments は .byte や .word の引数をカンマで分ける。
+func splitArguments(operand string) ([]string, error) {
+	if operand == "" {
+		return nil, nil
+	}
+
+	var arguments []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(operand); i++ {
+		switch c := operand[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			arguments = append(arguments, strings.TrimSpace(operand[start:i]))
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated string")
+	}
+	arguments = append(arguments, strings.TrimSpace(operand[start:]))
+
+	for _, argument := range arguments {
+		if argument == "" {
+			return nil, errors.New("empty value")
+		}
+	}
+
+	return arguments, nil
+}
+
+// opcodes はニーモニックとアドレッシングモードから命令のコードを引く表。
+// 同じ組み合わせに複数のコードがあるときは公式の命令を優先する。
+var opcodes = func() map[string]map[cpu.AddressingMode]byte {
+	opcodes := map[string]map[cpu.AddressingMode]byte{}
+	for _, unofficial := range []bool{false, true} {
+		for code := range 0x1_00 {
+			opcode, ok := cpu.LookupOpcode(byte(code))
+			if !ok || opcode.Unofficial != unofficial {
+				continue
+			}
+
+			if opcodes[opcode.Mnemonic] == nil {
+				opcodes[opcode.Mnemonic] = map[cpu.AddressingMode]byte{}
+			}
+			if _, ok := opcodes[opcode.Mnemonic][opcode.Mode]; !ok {
+				opcodes[opcode.Mnemonic][opcode.Mode] = byte(code)
+			}
+		}
+	}
+
+	return opcodes
+}()
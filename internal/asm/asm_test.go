@@ -0,0 +1,235 @@
+package asm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tabo-syu/famicom/internal/disasm"
+	"github.com/tabo-syu/famicom/internal/rom"
+)
+
+func Test_Assemble_AddressingModes(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []byte
+	}{
+		{name: "Implied", source: "inx", want: []byte{0xE8}},
+		{name: "Accumulator", source: "asl a", want: []byte{0x0A}},
+		{name: "Accumulator/Omitted", source: "lsr", want: []byte{0x4A}},
+		{name: "Immediate", source: "lda #$01", want: []byte{0xA9, 0x01}},
+		{name: "Immediate/Negative", source: "lda #-1", want: []byte{0xA9, 0xFF}},
+		{name: "ZeroPage", source: "lda $10", want: []byte{0xA5, 0x10}},
+		{name: "ZeroPageX", source: "lda $10,x", want: []byte{0xB5, 0x10}},
+		{name: "ZeroPageY", source: "ldx $10, Y", want: []byte{0xB6, 0x10}},
+		{name: "Absolute", source: "lda $0200", want: []byte{0xAD, 0x00, 0x02}},
+		{name: "AbsoluteX", source: "lda $0200,x", want: []byte{0xBD, 0x00, 0x02}},
+		{name: "AbsoluteY", source: "lda $0200,y", want: []byte{0xB9, 0x00, 0x02}},
+		// LDA にはゼロページ Y がないので絶対アドレスになる
+		{name: "AbsoluteY/NoZeroPageY", source: "lda $10,y", want: []byte{0xB9, 0x10, 0x00}},
+		{name: "Indirect", source: "jmp ($0200)", want: []byte{0x6C, 0x00, 0x02}},
+		{name: "IndirectX", source: "lda ($10,x)", want: []byte{0xA1, 0x10}},
+		{name: "IndirectY", source: "lda ($10),y", want: []byte{0xB1, 0x10}},
+		{name: "Relative", source: "bne *+4", want: []byte{0xD0, 0x02}},
+		{name: "Relative/Backward", source: "bne *", want: []byte{0xD0, 0xFE}},
+		{name: "Parenthesized", source: "lda ($08+$08)*2", want: []byte{0xA5, 0x20}},
+		{name: "Unofficial", source: "lax $10", want: []byte{0xA7, 0x10}},
+		// 公式の命令と同じ組み合わせは公式の命令を使う
+		{name: "PreferOfficial", source: "sbc #$01", want: []byte{0xE9, 0x01}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := Assemble(tt.source)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, program.Code)
+		})
+	}
+}
+
+func Test_Assemble_Expressions(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       int
+	}{
+		{expression: "$FF", want: 0xFF},
+		{expression: "%1010", want: 0b1010},
+		{expression: "100", want: 100},
+		{expression: "'a'", want: 'a'},
+		{expression: "1+2*3", want: 7},
+		{expression: "(1+2)*3", want: 9},
+		{expression: "10-2-3", want: 5},
+		{expression: "1<<4|1", want: 0x11},
+		{expression: "$1234>>8", want: 0x12},
+		{expression: "$F0&$3C^$01", want: 0x31},
+		{expression: "<$1234", want: 0x34},
+		{expression: ">$1234", want: 0x12},
+		{expression: "-1", want: -1},
+		{expression: "~0&$FF", want: 0xFF},
+		{expression: "base+1", want: 0x11},
+		{expression: "*", want: 0x80},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			a := &assembler{symbols: map[string]int{"base": 0x10}, address: 0x80}
+
+			got, err := a.evaluate(tt.expression)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_Assemble_LabelsAndDirectives(t *testing.T) {
+	source := `
+; 前方参照のラベルは絶対アドレスになる
+pointer = $10
+        .org $0600
+start:  lda #<table
+        sta pointer
+        lda #>table
+        sta pointer+1
+        jsr sub
+        beq start
+        brk
+sub:    lda table
+        rts
+table:  .byte 1, $02, "ab"
+        .word start, table+2
+`
+
+	program, err := Assemble(source)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(0x06_00), program.Origin)
+	assert.Equal(t, []byte{
+		0xA9, 0x12, // lda #<table
+		0x85, 0x10, // sta pointer
+		0xA9, 0x06, // lda #>table
+		0x85, 0x11, // sta pointer+1
+		0x20, 0x0E, 0x06, // jsr sub
+		0xF0, 0xF3, // beq start
+		0x00,             // brk
+		0xAD, 0x12, 0x06, // sub: lda table
+		0x60,                 // rts
+		0x01, 0x02, 'a', 'b', // table
+		0x00, 0x06, 0x14, 0x06,
+	}, program.Code)
+	assert.Equal(t, 0x06_12, program.Symbols["table"])
+	assert.Equal(t, 0x10, program.Symbols["pointer"])
+}
+
+func Test_Assemble_Org(t *testing.T) {
+	source := `
+        .org $0600
+        nop
+        .org $0604
+        .byte $FF
+`
+
+	program, err := Assemble(source)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(0x06_00), program.Origin)
+	// .org で飛ばした隙間は 0 で埋める
+	assert.Equal(t, []byte{0xEA, 0x00, 0x00, 0x00, 0xFF}, program.Code)
+}
+
+func Test_Assemble_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{name: "UnknownInstruction", source: "foo", want: "line 1: unknown instruction FOO"},
+		{name: "UnknownDirective", source: ".foo", want: "line 1: unknown directive .foo"},
+		{name: "UnsupportedMode", source: "jmp #$01", want: "line 1: JMP does not support this addressing mode"},
+		{name: "Undefined", source: "nop\nlda missing", want: "line 2: undefined symbol \"missing\""},
+		{name: "Duplicate", source: "a:\na:", want: "line 2: symbol \"a\" is already defined"},
+		{name: "BranchOutOfRange", source: "bne *+200", want: "line 1: branch target $00C8 is out of range"},
+		{name: "ZeroPageOutOfRange", source: "stx $0200,y", want: "line 1: zero page address $200 is out of range"},
+		{name: "ByteOutOfRange", source: ".byte 256", want: "line 1: value $100 does not fit in a byte"},
+		{name: "Overlap", source: "nop\n.org 0\nnop", want: "line 3: $0000 is already written"},
+		{name: "Overflow", source: ".org $FFFF\n.word 0", want: "line 2: program counter overflows $FFFF"},
+		{name: "BadExpression", source: "lda #(1", want: "line 1: missing ')' in expression \"(1\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Assemble(tt.source)
+
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
+
+func Test_Assemble_RoundTripWithDisassembler(t *testing.T) {
+	source := `
+        .org $0600
+        ldx #$00
+L0602:
+        inx
+        lda $0200,X
+        sta ($10),Y
+        cpx #$08
+        bne L0602
+        jmp ($0300)
+`
+
+	program, err := Assemble(source)
+	assert.NoError(t, err)
+
+	lines := disasm.Disassemble(program.Code, program.Origin)
+	want := []string{"LDX #$00", "INX", "LDA $0200,X", "STA ($10),Y", "CPX #$08", "BNE L0602", "JMP ($0300)"}
+	got := make([]string, len(lines))
+	for i, line := range lines {
+		got[i] = line.Text
+	}
+	assert.Equal(t, want, got)
+}
+
+func Test_Program_INES(t *testing.T) {
+	source := `
+        .org $C000
+reset:  jmp reset
+        .org $FFFA
+        .word reset, reset, reset
+`
+
+	program, err := Assemble(source)
+	assert.NoError(t, err)
+
+	raw, err := program.INES()
+	assert.NoError(t, err)
+
+	cartridge, err := rom.NewROM(raw)
+	assert.NoError(t, err)
+	assert.Len(t, cartridge.Prg, rom.PrgROMPageSize)
+	assert.Empty(t, cartridge.Chr)
+	assert.Equal(t, uint16(0), cartridge.Mapper)
+	assert.Equal(t, []byte{0x4C, 0x00, 0xC0}, cartridge.Prg[:3])
+	// リセットベクタ
+	assert.Equal(t, []byte{0x00, 0xC0}, cartridge.Prg[len(cartridge.Prg)-4:len(cartridge.Prg)-2])
+}
+
+func Test_Program_INES_32KB(t *testing.T) {
+	program, err := Assemble(".org $8000\nnop\n.org $FFFC\n.word $8000")
+	assert.NoError(t, err)
+
+	raw, err := program.INES()
+
+	assert.NoError(t, err)
+	assert.Len(t, raw, rom.HeaderSize+2*rom.PrgROMPageSize)
+	assert.Equal(t, byte(2), raw[4])
+	assert.Equal(t, byte(0xEA), raw[rom.HeaderSize])
+}
+
+func Test_Program_INES_OutsidePrgROM(t *testing.T) {
+	program, err := Assemble(".org $0600\nnop")
+	assert.NoError(t, err)
+
+	_, err = program.INES()
+
+	assert.EqualError(t, err, "program at $0600 is outside PRG ROM ($8000-$FFFF)")
+}
@@ -0,0 +1,260 @@
+package asm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// errUndefined は式がまだ定義されていないシンボルを参照していることを表す。
+// 1 パス目では前方参照として扱い、2 パス目ではエラーにする。
+var errUndefined = errors.New("undefined symbol")
+
+// evaluate は式 s を評価する。使える書き方は次のとおり。
+//
+//	$FF %1010 255 'a'    数値と文字
+//	label *              シンボルと現在のアドレス
+//	- ~ < >              単項演算子 (< は下位バイト、> は上位バイト)
+//	* / + - << >> & ^ |  二項演算子 (C と同じ優先順位)
+//	( )                  括弧
+func (a *assembler) evaluate(s string) (int, error) {
+	p := &parser{source: s, assembler: a}
+
+	value, err := p.binary(0)
+	if err != nil {
+		return 0, err
+	}
+
+	p.skipSpace()
+	if p.position < len(p.source) {
+		return 0, fmt.Errorf("unexpected %q in expression %q", p.source[p.position:], s)
+	}
+
+	return value, nil
+}
+
+type parser struct {
+	source    string
+	position  int
+	assembler *assembler
+}
+
+// binaryOperators は優先順位の低い順に並べた二項演算子。
+var binaryOperators = [][]string{
+	{"|"},
+	{"^"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/"},
+}
+
+// binary は優先順位 level 以上の二項演算子からなる式を読む。
+// 未定義のシンボルがあっても式の終わりまで読み進め、最後に errUndefined を返す。
+func (p *parser) binary(level int) (int, error) {
+	if level == len(binaryOperators) {
+		return p.unary()
+	}
+
+	left, undefined := p.binary(level + 1)
+	if undefined != nil && !errors.Is(undefined, errUndefined) {
+		return 0, undefined
+	}
+
+	for {
+		operator, ok := p.operator(binaryOperators[level])
+		if !ok {
+			return left, undefined
+		}
+
+		right, err := p.binary(level + 1)
+		if err != nil {
+			if !errors.Is(err, errUndefined) {
+				return 0, err
+			}
+			if undefined == nil {
+				undefined = err
+			}
+		}
+
+		switch operator {
+		case "|":
+			left |= right
+		case "^":
+			left ^= right
+		case "&":
+			left &= right
+		case "<<":
+			left <<= right
+		case ">>":
+			left >>= right
+		case "+":
+			left += right
+		case "-":
+			left -= right
+		case "*":
+			left *= right
+		case "/":
+			if right == 0 {
+				if undefined != nil {
+					continue
+				}
+
+				return 0, errors.New("division by zero")
+			}
+			left /= right
+		}
+	}
+}
+
+// operator は次が operators のいずれかであれば読み進めてそれを返す。
+func (p *parser) operator(operators []string) (string, bool) {
+	p.skipSpace()
+	rest := p.source[p.position:]
+	for _, operator := range operators {
+		if !strings.HasPrefix(rest, operator) {
+			continue
+		}
+
+		p.position += len(operator)
+
+		return operator, true
+	}
+
+	return "", false
+}
+
+func (p *parser) unary() (int, error) {
+	p.skipSpace()
+	if p.position >= len(p.source) {
+		return 0, fmt.Errorf("missing operand in expression %q", p.source)
+	}
+
+	operator := p.source[p.position]
+	switch operator {
+	case '-', '~', '<', '>':
+		p.position++
+		value, err := p.unary()
+
+		switch operator {
+		case '-':
+			value = -value
+		case '~':
+			value = ^value
+		case '<':
+			value &= 0xFF
+		case '>':
+			value = (value >> 8) & 0xFF
+		}
+
+		return value, err
+	default:
+		return p.primary()
+	}
+}
+
+func (p *parser) primary() (int, error) {
+	rest := p.source[p.position:]
+
+	switch c := rest[0]; {
+	case c == '(':
+		p.position++
+		value, err := p.binary(0)
+		if err != nil && !errors.Is(err, errUndefined) {
+			return 0, err
+		}
+
+		p.skipSpace()
+		if p.position >= len(p.source) || p.source[p.position] != ')' {
+			return 0, fmt.Errorf("missing ')' in expression %q", p.source)
+		}
+		p.position++
+
+		return value, err
+
+	case c == '*':
+		p.position++
+
+		return p.assembler.address, nil
+
+	case c == '\'':
+		if len(rest) < 3 || rest[2] != '\'' {
+			return 0, fmt.Errorf("invalid character literal in expression %q", p.source)
+		}
+		p.position += 3
+
+		return int(rest[1]), nil
+
+	case c == '$':
+		return p.number(rest[1:], 16, 1)
+
+	case c == '%':
+		return p.number(rest[1:], 2, 1)
+
+	case isDigit(c):
+		return p.number(rest, 10, 0)
+
+	case isIdentifierStart(c):
+		name := identifier(rest)
+		p.position += len(name)
+
+		value, ok := p.assembler.symbols[name]
+		if !ok {
+			return 0, fmt.Errorf("%w %q", errUndefined, name)
+		}
+
+		return value, nil
+
+	default:
+		return 0, fmt.Errorf("unexpected %q in expression %q", rest, p.source)
+	}
+}
+
+// number は prefix バイトの接頭辞に続く base 進数の数値を読む。
+func (p *parser) number(s string, base int, prefix int) (int, error) {
+	end := 0
+	for end < len(s) && isIdentifierPart(s[end]) {
+		end++
+	}
+
+	value, err := strconv.ParseUint(s[:end], base, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", p.source[p.position:p.position+prefix+end])
+	}
+	p.position += prefix + end
+
+	return int(value), nil
+}
+
+func (p *parser) skipSpace() {
+	for p.position < len(p.source) && (p.source[p.position] == ' ' || p.source[p.position] == '\t') {
+		p.position++
+	}
+}
+
+// identifier は s の先頭にある識別子を返す。
+func identifier(s string) string {
+	if s == "" || !isIdentifierStart(s[0]) {
+		return ""
+	}
+
+	end := 1
+	for end < len(s) && isIdentifierPart(s[end]) {
+		end++
+	}
+
+	return s[:end]
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || isDigit(c)
+}
@@ -0,0 +1,37 @@
+package asm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tabo-syu/famicom/internal/rom"
+)
+
+// INES はプログラムを NROM (マッパー 0) の iNES イメージにする。
+// プログラムが 0xC000 以降に収まれば PRG ROM は 16KB、そうでなければ 32KB になる。
+// CHR ROM は持たず、PPU は CHR RAM を使う。
+// ベクタはソースで `.org $FFFA` に続けて `.word nmi, reset, irq` のように置く。
+func (p *Program) INES() ([]byte, error) {
+	if len(p.Code) == 0 {
+		return nil, errors.New("program is empty")
+	}
+
+	const prgROMStart = 0x80_00
+	if p.Origin < prgROMStart {
+		return nil, fmt.Errorf("program at $%04X is outside PRG ROM ($8000-$FFFF)", p.Origin)
+	}
+
+	pages := 2
+	if p.Origin >= prgROMStart+rom.PrgROMPageSize {
+		pages = 1
+	}
+	bankStart := 0x1_00_00 - pages*rom.PrgROMPageSize
+
+	raw := make([]byte, rom.HeaderSize+pages*rom.PrgROMPageSize)
+	copy(raw, []byte{'N', 'E', 'S', 0x1A})
+	raw[4] = byte(pages) // PRG ROM: 16KB * pages
+	raw[5] = 0           // CHR ROM: なし (CHR RAM)
+	copy(raw[rom.HeaderSize+int(p.Origin)-bankStart:], p.Code)
+
+	return raw, nil
+}
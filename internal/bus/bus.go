@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"github.com/tabo-syu/famicom/internal/memory"
+	"github.com/tabo-syu/famicom/internal/ppu"
 	"github.com/tabo-syu/famicom/internal/rom"
 )
 
@@ -13,32 +14,80 @@ const (
 	RAMMirrorsEnd          uint16 = 0x1F_FF
 	PPURegisters           uint16 = 0x20_00
 	PPURegistersMirrorsEnd uint16 = 0x3F_FF
+	OAMDMA                 uint16 = 0x40_14
+	// APUIORegisters から APUIORegistersEnd まで (OAMDMA を除く) は APU とコントローラーのレジスタ。
+	// まだ実装していないため、読み込みは 0 を返し、書き込みは無視する。
+	APUIORegisters    uint16 = 0x40_00
+	APUIORegistersEnd uint16 = 0x40_1F
+	// Cartridge から 0xFFFF まではカートリッジのマッパーが受け持つ。
+	Cartridge uint16 = 0x40_20
 )
 
 type Bus interface {
 	ReadMemory(address uint16) byte
+	PeekMemory(address uint16) byte
 	ReadMemoryUint16(address uint16) uint16
 	WriteMemory(address uint16, data byte)
 	WriteMemoryUint16(address uint16, data uint16)
 	CopyToMemory(start int, value []byte)
-	ReadPrgROM(address uint16) byte
+	Tick(cycles uint16)
+	PollNMI() bool
+	FrameCount() uint64
+	PPUPosition() (scanline int, dot int)
+	IRQ() bool
+	SetIRQ(source IRQSource, active bool)
 }
 
+// IRQSource は IRQ 線を駆動するデバイス。
+// IRQ 線はいずれかのデバイスがアサートしている間アクティブになる。
+type IRQSource byte
+
+const (
+	IRQMapper IRQSource = 1 << iota
+	IRQFrameCounter
+	IRQDMC
+)
+
 type bus struct {
 	Memory memory.Memory
-	ROM    *rom.ROM
+	Mapper rom.Mapper
+	PPU    *ppu.PPU
+
+	// clock は CPU のサイクルを数えるマッパー。マッパーが rom.Clocked でなければ nil。
+	clock rom.Clocked
+	// irq は IRQ 線をアサートしているデバイス。
+	irq IRQSource
 }
 
-func NewBus(memory memory.Memory, rom *rom.ROM) *bus {
+// NewBus は cartridge のマッパーをつないだバスを作る。
+// cartridge のマッパーに対応していなければエラーを返す。
+func NewBus(memory memory.Memory, cartridge *rom.ROM) (*bus, error) {
+	mapper, err := rom.NewMapper(cartridge)
+	if err != nil {
+		return nil, err
+	}
+
+	clock, _ := mapper.(rom.Clocked)
+
 	return &bus{
 		Memory: memory,
-		ROM:    rom,
-	}
+		Mapper: mapper,
+		PPU:    ppu.NewPPU(mapper, mapper.Mirroring()),
+		clock:  clock,
+	}, nil
 }
 
 func (bus *bus) ReadMemory(address uint16) byte {
-	if 0x8000 <= address && address <= 0xFFFF {
-		return bus.ReadPrgROM(address)
+	if address >= Cartridge {
+		return bus.Mapper.ReadPRG(address)
+	}
+
+	if PPURegisters <= address && address <= PPURegistersMirrorsEnd {
+		return bus.PPU.ReadRegister(address)
+	}
+
+	if APUIORegisters <= address && address <= APUIORegistersEnd {
+		return 0x00
 	}
 
 	masked, err := bus.mask(address)
@@ -51,14 +100,35 @@ func (bus *bus) ReadMemory(address uint16) byte {
 	return bus.Memory.Read(masked)
 }
 
+// PeekMemory は ReadMemory と同じ値を、PPU レジスタの状態を変えずに返す。
+// トレースなどで、実行に影響を与えずにメモリを覗くために使う。
+func (bus *bus) PeekMemory(address uint16) byte {
+	if PPURegisters <= address && address <= PPURegistersMirrorsEnd {
+		return bus.PPU.PeekRegister(address)
+	}
+
+	return bus.ReadMemory(address)
+}
+
 func (bus *bus) ReadMemoryUint16(address uint16) uint16 {
-	if 0x8000 <= address && address <= 0xFFFF {
-		low := uint16(bus.ReadPrgROM(address))
-		high := uint16(bus.ReadPrgROM(address + 1))
+	if address >= Cartridge {
+		low := uint16(bus.Mapper.ReadPRG(address))
+		high := uint16(bus.Mapper.ReadPRG(address + 1))
+
+		return high<<8 | low
+	}
+
+	if PPURegisters <= address && address <= PPURegistersMirrorsEnd {
+		low := uint16(bus.PPU.ReadRegister(address))
+		high := uint16(bus.PPU.ReadRegister(address + 1))
 
 		return high<<8 | low
 	}
 
+	if APUIORegisters <= address && address <= APUIORegistersEnd {
+		return 0x00
+	}
+
 	masked, err := bus.mask(address)
 	if err != nil {
 		log.Println(err)
@@ -70,8 +140,26 @@ func (bus *bus) ReadMemoryUint16(address uint16) uint16 {
 }
 
 func (bus *bus) WriteMemory(address uint16, data byte) {
-	if 0x8000 <= address && address <= 0xFFFF {
-		panic("Attempt to write to Cartridge ROM space")
+	if address >= Cartridge {
+		bus.writeCartridge(address, data)
+
+		return
+	}
+
+	if PPURegisters <= address && address <= PPURegistersMirrorsEnd {
+		bus.PPU.WriteRegister(address, data)
+
+		return
+	}
+
+	if address == OAMDMA {
+		bus.writeOAMDMA(data)
+
+		return
+	}
+
+	if APUIORegisters <= address && address <= APUIORegistersEnd {
+		return
 	}
 
 	masked, err := bus.mask(address)
@@ -85,6 +173,24 @@ func (bus *bus) WriteMemory(address uint16, data byte) {
 }
 
 func (bus *bus) WriteMemoryUint16(address uint16, data uint16) {
+	if address >= Cartridge {
+		bus.writeCartridge(address, byte(data&0x00_FF))
+		bus.writeCartridge(address+1, byte(data>>8))
+
+		return
+	}
+
+	if PPURegisters <= address && address <= PPURegistersMirrorsEnd {
+		bus.PPU.WriteRegister(address, byte(data&0x00_FF))
+		bus.PPU.WriteRegister(address+1, byte(data>>8))
+
+		return
+	}
+
+	if APUIORegisters <= address && address <= APUIORegistersEnd {
+		return
+	}
+
 	masked, err := bus.mask(address)
 	if err != nil {
 		log.Println(err)
@@ -99,13 +205,60 @@ func (bus *bus) CopyToMemory(start int, value []byte) {
 	bus.Memory.Copy(start, value)
 }
 
-func (bus *bus) ReadPrgROM(address uint16) byte {
-	address -= 0x8000
-	if len(bus.ROM.Prg) == 0x4000 && address >= 0x4000 {
-		address = address % 0x4000
+// writeCartridge はマッパーに書き込み、マッパーが切り替えたミラーリングと IRQ を PPU と IRQ 線に反映する。
+// MMC3 の 0xE000 のように書き込みで IRQ を取り下げるマッパーがあるため、次の Tick を待たずに反映する。
+func (bus *bus) writeCartridge(address uint16, data byte) {
+	bus.Mapper.WritePRG(address, data)
+	bus.PPU.SetMirroring(bus.Mapper.Mirroring())
+	bus.SetIRQ(IRQMapper, bus.Mapper.IRQ())
+}
+
+// writeOAMDMA は CPU の 0xXX00-0xXXFF (XX = page) を PPU の OAM に転送する。
+func (bus *bus) writeOAMDMA(page byte) {
+	var data [0x1_00]byte
+	for i := range data {
+		data[i] = bus.ReadMemory(uint16(page)<<8 | uint16(i))
+	}
+
+	bus.PPU.WriteOAMDMA(data)
+}
+
+// Tick は CPU が cycles サイクル進んだ分だけ PPU とマッパーを進め、マッパーの IRQ を IRQ 線に反映する。
+func (bus *bus) Tick(cycles uint16) {
+	bus.PPU.Tick(cycles * 3)
+	if bus.clock != nil {
+		bus.clock.Tick(cycles)
 	}
+	bus.SetIRQ(IRQMapper, bus.Mapper.IRQ())
+}
+
+// PollNMI は PPU が NMI を発生させたかどうかを返す。
+func (bus *bus) PollNMI() bool {
+	return bus.PPU.PollNMI()
+}
+
+// FrameCount は PPU が描き終えたフレームの数を返す。
+func (bus *bus) FrameCount() uint64 {
+	return bus.PPU.FrameCount()
+}
+
+// PPUPosition は PPU が次に処理するスキャンラインとドットを返す。
+func (bus *bus) PPUPosition() (int, int) {
+	return bus.PPU.Position()
+}
+
+// IRQ は IRQ 線がアクティブかどうかを返す。
+func (bus *bus) IRQ() bool {
+	return bus.irq != 0
+}
 
-	return bus.ROM.Prg[address]
+// SetIRQ は source による IRQ 線のアサートを切り替える。
+func (bus *bus) SetIRQ(source IRQSource, active bool) {
+	if active {
+		bus.irq |= source
+	} else {
+		bus.irq &^= source
+	}
 }
 
 func (bus *bus) mask(address uint16) (uint16, error) {
@@ -117,7 +270,6 @@ func (bus *bus) mask(address uint16) (uint16, error) {
 	switch {
 	case RAM <= address && address <= RAMMirrorsEnd:
 		masked = address & 0b0000_0111_1111_1111
-	case PPURegisters <= address && address <= PPURegistersMirrorsEnd:
 	default:
 		err = fmt.Errorf("ignoring memory access at %#x", address)
 	}
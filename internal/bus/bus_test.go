@@ -0,0 +1,63 @@
+package bus
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tabo-syu/famicom/internal/memory"
+	"github.com/tabo-syu/famicom/internal/rom"
+)
+
+func Test_NewBus_UnsupportedMapper(t *testing.T) {
+	memory := memory.NewMemory()
+
+	bus, err := NewBus(&memory, &rom.ROM{Mapper: 150})
+
+	assert.Nil(t, bus)
+	assert.EqualError(t, err, "unsupported mapper 150")
+}
+
+func Test_APUIORegisters_OpenBus(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	memory := memory.NewMemory()
+	bus, err := NewBus(&memory, &rom.ROM{Prg: make([]byte, rom.PrgROMPageSize)})
+	assert.NoError(t, err)
+
+	for address := APUIORegisters; address <= APUIORegistersEnd; address++ {
+		if address == OAMDMA {
+			continue
+		}
+		bus.WriteMemory(address, 0xFF)
+		assert.Equal(t, byte(0x00), bus.ReadMemory(address), "$%04X", address)
+		assert.Equal(t, byte(0x00), bus.PeekMemory(address), "$%04X", address)
+	}
+	bus.WriteMemoryUint16(APUIORegisters, 0xFF_FF)
+	assert.Equal(t, uint16(0x00_00), bus.ReadMemoryUint16(APUIORegisters))
+
+	// APU とコントローラーのレジスタへのアクセスはログに出さない
+	assert.Empty(t, logs.String())
+}
+
+func Test_WriteMemory_AcknowledgeMapperIRQ(t *testing.T) {
+	memory := memory.NewMemory()
+	// マッパー 4 (MMC3)
+	bus, err := NewBus(&memory, &rom.ROM{Mapper: 4, Prg: make([]byte, 2*rom.PrgROMPageSize)})
+	assert.NoError(t, err)
+	// IRQ latch を 0 にして IRQ を有効にし、A12 の立ち上がりで IRQ を出させる
+	bus.WriteMemory(0xC0_00, 0)
+	bus.WriteMemory(0xE0_01, 0)
+	bus.Mapper.ReadCHR(0x00_00)
+	bus.Mapper.ReadCHR(0x10_00)
+	bus.Tick(0)
+	assert.True(t, bus.IRQ())
+
+	// 0xE000 への書き込みで取り下げた IRQ は、次の Tick を待たずに IRQ 線から消える
+	bus.WriteMemory(0xE0_00, 0)
+	assert.False(t, bus.IRQ())
+}
@@ -1,7 +1,9 @@
 package cpu
 
 import (
-	"log"
+	"context"
+	"io"
+	"sync"
 	"time"
 
 	"github.com/tabo-syu/famicom/internal/bus"
@@ -16,9 +18,32 @@ type CPU struct {
 	status         status
 
 	Bus          bus.Bus
-	Instructions map[byte]instruction
+	Instructions [256]instruction
+
+	// Variant は CPU の種類。ゼロ値はファミコンの RP2A03。
+	Variant Variant
+	// HaltOnBRK が true のとき、BRK は割り込みを起こさずに実行を止める。
+	HaltOnBRK bool
+	// ClockRate は Run が実行する 1 秒あたりのサイクル数。0 なら速度を制限しない。
+	ClockRate uint64
+	// Tracer が nil でなければ、命令を実行する前に nestest.log 形式のトレースを書き出す。
+	Tracer io.Writer
+
+	// cycles は電源投入から経過したサイクル数。
+	cycles uint64
+	// pageCrossed は実行中の命令のインデックス付きアドレッシングがページをまたいだかどうか。
+	pageCrossed bool
+	// extraCycles は実行中の命令で分岐やページまたぎによって増えたサイクル数。
+	extraCycles uint16
+
+	pauseMu sync.Mutex
+	// resumed は一時停止中に作られ、再開すると閉じられる。
+	resumed chan struct{}
 }
 
+// NTSCClockRate は NTSC の NES の CPU クロック周波数 (Hz)。
+const NTSCClockRate = 1_789_773
+
 func NewCPU(bus bus.Bus) CPU {
 	return CPU{
 		ProgramCounter: 0,
@@ -29,7 +54,8 @@ func NewCPU(bus bus.Bus) CPU {
 		status:         0,
 
 		Bus:          bus,
-		Instructions: NewInstructions(),
+		Instructions: instructionTable,
+		ClockRate:    NTSCClockRate,
 	}
 }
 
@@ -47,25 +73,165 @@ func (cpu *CPU) Reset(position uint16) {
 	cpu.registerY = 0
 	cpu.stackPointer = newStackPointer()
 	cpu.status = newStatus()
+
+	// リセットシーケンスには 7 サイクルかかる
+	cpu.cycles = 0
+	cpu.tick(7)
+}
+
+// Cycles は電源投入から経過したサイクル数を返す。
+func (cpu *CPU) Cycles() uint64 {
+	return cpu.cycles
 }
 
-func (cpu *CPU) Run() {
+// tick は cycles サイクル経過させ、その分だけバスの先のデバイスを進める。
+func (cpu *CPU) tick(cycles uint16) {
+	cpu.cycles += uint64(cycles)
+	cpu.Bus.Tick(cycles)
+}
+
+// Step は発生している割り込みを処理してから命令を 1 つ実行し、掛かったサイクル数を返す。
+func (cpu *CPU) Step() (uint64, error) {
+	start := cpu.cycles
+	cpu.pollInterrupts()
+
+	if cpu.Tracer != nil {
+		cpu.trace(cpu.Tracer)
+	}
+
+	address := cpu.ProgramCounter
+	code := cpu.Bus.ReadMemory(address)
+	if jams(code) {
+		return cpu.cycles - start, &JamError{Opcode: code, Address: address}
+	}
+	instruction := &cpu.Instructions[code]
+	if instruction.operation == nil {
+		return cpu.cycles - start, &UnknownOpcodeError{Opcode: code, Address: address}
+	}
+	cpu.ProgramCounter++
+
+	cpu.pageCrossed = false
+	cpu.extraCycles = 0
+	// PPU レジスタへのアクセスは命令の最後のサイクルで起きることが多いため、
+	// 命令を実行する前に命令のサイクル数だけ PPU を進めておく
+	cpu.tick(instruction.cycles)
+	if err := instruction.Call(cpu); err != nil {
+		return cpu.cycles - start, err
+	}
+	if instruction.pageCrossCycle && cpu.pageCrossed {
+		cpu.extraCycles++
+	}
+	cpu.tick(cpu.extraCycles)
+
+	return cpu.cycles - start, nil
+}
+
+// RunFor は少なくとも cycles サイクル経過するまで命令を実行する。
+// 命令の途中では止まれないため、cycles を数サイクル超えることがある。
+func (cpu *CPU) RunFor(cycles uint64) error {
+	for elapsed := uint64(0); elapsed < cycles; {
+		n, err := cpu.Step()
+		if err != nil {
+			return err
+		}
+		elapsed += n
+	}
+
+	return nil
+}
+
+// RunFrame は PPU が次のフレームを描き終える (vblank に入る) まで命令を実行する。
+func (cpu *CPU) RunFrame() error {
+	frame := cpu.Bus.FrameCount()
+	for cpu.Bus.FrameCount() == frame {
+		if _, err := cpu.Step(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runBatchCycles は Run が一時停止・キャンセル・実行速度を確かめる間隔のサイクル数。NTSC でおよそ 1 ミリ秒。
+const runBatchCycles = NTSCClockRate / 1_000
+
+// Run は ctx がキャンセルされるか、命令の実行がエラーになるまで命令を実行し、止まった理由を返す。
+// 実行速度は ClockRate に合わせる。一時停止とキャンセルは runBatchCycles サイクルごとに確かめる。
+func (cpu *CPU) Run(ctx context.Context) error {
+	start, startCycles := time.Now(), cpu.cycles
+
 	for {
-		code := cpu.Bus.ReadMemory(cpu.ProgramCounter)
-		cpu.ProgramCounter++
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
-		if err := cpu.Instructions[code].Call(cpu); err != nil {
-			log.Println(err)
+		if resumed := cpu.pausedUntil(); resumed != nil {
+			select {
+			case <-resumed:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			// 一時停止していた時間の分を取り戻そうとしないように速度の基準をやり直す
+			start, startCycles = time.Now(), cpu.cycles
+		}
 
-			break
+		if err := cpu.RunFor(runBatchCycles); err != nil {
+			return err
 		}
 
-		time.Sleep(10 * time.Microsecond)
+		cpu.pace(start, startCycles)
+	}
+}
+
+// Pause は Run を一時停止させる。Run は実行中の runBatchCycles サイクル分を終えてから止まる。
+func (cpu *CPU) Pause() {
+	cpu.pauseMu.Lock()
+	defer cpu.pauseMu.Unlock()
+
+	if cpu.resumed == nil {
+		cpu.resumed = make(chan struct{})
+	}
+}
+
+// Resume は一時停止した Run を再開させる。
+func (cpu *CPU) Resume() {
+	cpu.pauseMu.Lock()
+	defer cpu.pauseMu.Unlock()
+
+	if cpu.resumed != nil {
+		close(cpu.resumed)
+		cpu.resumed = nil
 	}
 }
 
-func (cpu *CPU) LoadAndRun(program []byte) {
+// Paused は一時停止中かどうかを返す。
+func (cpu *CPU) Paused() bool {
+	return cpu.pausedUntil() != nil
+}
+
+// pausedUntil は一時停止中なら再開時に閉じられるチャネルを、そうでなければ nil を返す。
+func (cpu *CPU) pausedUntil() chan struct{} {
+	cpu.pauseMu.Lock()
+	defer cpu.pauseMu.Unlock()
+
+	return cpu.resumed
+}
+
+// pace は start から実行したサイクル数が ClockRate で掛かる時間より実時間が遅れないように待つ。
+func (cpu *CPU) pace(start time.Time, startCycles uint64) {
+	if cpu.ClockRate == 0 {
+		return
+	}
+
+	emulated := time.Duration(float64(cpu.cycles-startCycles) / float64(cpu.ClockRate) * float64(time.Second))
+	if ahead := emulated - time.Since(start); ahead > time.Millisecond {
+		time.Sleep(ahead)
+	}
+}
+
+func (cpu *CPU) LoadAndRun(ctx context.Context, program []byte) error {
 	cpu.Load(program)
 	cpu.Reset(0xFF_FC)
-	cpu.Run()
+
+	return cpu.Run(ctx)
 }
@@ -1,7 +1,11 @@
 package cpu
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/tabo-syu/famicom/internal/bus"
@@ -12,10 +16,35 @@ import (
 var validrom = []byte{
 	'N', 'E', 'S', 0x1A,
 	0x00, 0x00,
-	0b0110_0001, 0b1001_0000,
+	0b0000_0001, 0b0000_0000,
 	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 }
 
+// romWithVectorsForTest は NMI ベクタが nmi を、IRQ/BRK ベクタが irq を指す PRG ROM を持った iNES イメージを作る。
+func romWithVectorsForTest(nmi uint16, irq uint16) []byte {
+	raw := append([]byte{}, validrom...)
+	raw[4] = 0x01
+	prg := make([]byte, 0x40_00)
+	prg[0x3F_FA] = byte(nmi)
+	prg[0x3F_FB] = byte(nmi >> 8)
+	prg[0x3F_FE] = byte(irq)
+	prg[0x3F_FF] = byte(irq >> 8)
+
+	return append(raw, prg...)
+}
+
+// newBusForTest は cartridge をつないだバスを作る。
+func newBusForTest(t testing.TB, memory memory.Memory, cartridge *rom.ROM) bus.Bus {
+	t.Helper()
+
+	b, err := bus.NewBus(memory, cartridge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
 func (cpu *CPU) loadForTest(program []byte) {
 	cpu.Bus.CopyToMemory(0x03_00, program)
 	cpu.Bus.WriteMemoryUint16(0x00_00, 0x03_00)
@@ -24,11 +53,12 @@ func (cpu *CPU) loadForTest(program []byte) {
 func Test_ADC_SetCarryFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x69, 0b1111_1111, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b0000_0001
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
 	assert.False(t, cpu.status.n())
@@ -40,11 +70,12 @@ func Test_ADC_SetCarryFlag(t *testing.T) {
 func Test_ADC_SetOverflowFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x69, 0b0111_1111, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b0000_0010
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.False(t, cpu.status.c())
 	assert.True(t, cpu.status.n())
@@ -56,11 +87,12 @@ func Test_ADC_SetOverflowFlag(t *testing.T) {
 func Test_AND_SetZeroFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x29, 0b0000_0000, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b0000_0000
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.z())
 	assert.False(t, cpu.status.n())
@@ -69,11 +101,12 @@ func Test_AND_SetZeroFlag(t *testing.T) {
 func Test_AND_SetNegativeFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x29, 0b1000_0000, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b1000_0000
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.False(t, cpu.status.z())
 	assert.True(t, cpu.status.n())
@@ -82,11 +115,12 @@ func Test_AND_SetNegativeFlag(t *testing.T) {
 func Test_ASL_ArithmeticShiftLeft(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x0A, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b1101_0101
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
 	assert.False(t, cpu.status.z())
@@ -97,14 +131,16 @@ func Test_ASL_ArithmeticShiftLeft(t *testing.T) {
 func Test_ASL_ShiftFromMemory(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x06, 0x05, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x05, 0b1101_0101)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
-	assert.True(t, cpu.status.z())
+	// Z は A ではなく書き込んだ値で決まる
+	assert.False(t, cpu.status.z())
 	assert.True(t, cpu.status.n())
 	assert.Equal(t, byte(0b1010_1010), cpu.Bus.ReadMemory(0x05))
 }
@@ -112,13 +148,14 @@ func Test_ASL_ShiftFromMemory(t *testing.T) {
 func Test_BCC_WhenSetCarry(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	// 0x0300, 0x0301, 0x0302, 0x0303
 	cpu.loadForTest([]byte{0x90, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setC(true)
 	cpu.Bus.WriteMemory(0x03_10, 0xE8)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x00), cpu.registerX)
 	assert.Equal(t, uint16(0x03_03), cpu.ProgramCounter)
@@ -127,14 +164,15 @@ func Test_BCC_WhenSetCarry(t *testing.T) {
 func Test_BCC_WhenUnsetCarry(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	// 0x0300, 0x0301, 0x0312, 0x0313
 	cpu.loadForTest([]byte{0x90, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setC(false)
 	cpu.Bus.WriteMemory(0x03_12, 0xE8)
 	cpu.Bus.WriteMemory(0x03_13, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x03_14), cpu.ProgramCounter)
@@ -143,14 +181,15 @@ func Test_BCC_WhenUnsetCarry(t *testing.T) {
 func Test_BCC_WhenMinusOperand(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	// 0x0300, 0x0301, 0x0312, 0x0313
 	cpu.loadForTest([]byte{0x90, 0xF6, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setC(false)
 	cpu.Bus.WriteMemory(0x02_F8, 0xE8)
 	cpu.Bus.WriteMemory(0x02_F9, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x02_FA), cpu.ProgramCounter)
@@ -159,14 +198,15 @@ func Test_BCC_WhenMinusOperand(t *testing.T) {
 func Test_BCS_WhenSetCarry(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	// 0x0300, 0x0301, 0x0312, 0x0313
 	cpu.loadForTest([]byte{0xB0, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setC(true)
 	cpu.Bus.WriteMemory(0x03_12, 0xE8)
 	cpu.Bus.WriteMemory(0x03_13, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x03_14), cpu.ProgramCounter)
@@ -175,13 +215,14 @@ func Test_BCS_WhenSetCarry(t *testing.T) {
 func Test_BCS_WhenUnsetCarry(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	// 0x0300, 0x0301, 0x0302, 0x0303
 	cpu.loadForTest([]byte{0xB0, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setC(false)
 	cpu.Bus.WriteMemory(0x03_10, 0xE8)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x00), cpu.registerX)
 	assert.Equal(t, uint16(0x03_03), cpu.ProgramCounter)
@@ -190,14 +231,15 @@ func Test_BCS_WhenUnsetCarry(t *testing.T) {
 func Test_BCS_WhenMinusOperand(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	// 0x0300, 0x0301, 0x0312, 0x0313
 	cpu.loadForTest([]byte{0xB0, 0xF6, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setC(true)
 	cpu.Bus.WriteMemory(0x02_F8, 0xE8)
 	cpu.Bus.WriteMemory(0x02_F9, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x02_FA), cpu.ProgramCounter)
@@ -206,13 +248,14 @@ func Test_BCS_WhenMinusOperand(t *testing.T) {
 func Test_BEQ_WhenSetZero(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xF0, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setZ(true)
 	cpu.Bus.WriteMemory(0x03_12, 0xE8)
 	cpu.Bus.WriteMemory(0x03_13, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x03_14), cpu.ProgramCounter)
@@ -221,12 +264,13 @@ func Test_BEQ_WhenSetZero(t *testing.T) {
 func Test_BEQ_WhenUnsetZero(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xF0, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setZ(false)
 	cpu.Bus.WriteMemory(0x03_10, 0xE8)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x00), cpu.registerX)
 	assert.Equal(t, uint16(0x03_03), cpu.ProgramCounter)
@@ -235,13 +279,14 @@ func Test_BEQ_WhenUnsetZero(t *testing.T) {
 func Test_BEQ_WhenMinusOperand(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xF0, 0xF6, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setZ(true)
 	cpu.Bus.WriteMemory(0x02_F8, 0xE8)
 	cpu.Bus.WriteMemory(0x02_F9, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x02_FA), cpu.ProgramCounter)
@@ -250,12 +295,13 @@ func Test_BEQ_WhenMinusOperand(t *testing.T) {
 func Test_BIT_SetNegativeFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x24, 0x05, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b1010_1010
 	cpu.Bus.WriteMemory(0x05, 0b1111_0000)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.False(t, cpu.status.z())
 	assert.True(t, cpu.status.n())
@@ -265,12 +311,13 @@ func Test_BIT_SetNegativeFlag(t *testing.T) {
 func Test_BIT_SetOverflowFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x24, 0x05, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b1100_1111
 	cpu.Bus.WriteMemory(0x05, 0b1111_0000)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.False(t, cpu.status.z())
 	assert.True(t, cpu.status.n())
@@ -280,12 +327,13 @@ func Test_BIT_SetOverflowFlag(t *testing.T) {
 func Test_BIT_SetZeroFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x24, 0x05, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b0000_0000
 	cpu.Bus.WriteMemory(0x05, 0b1111_0000)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.z())
 	assert.False(t, cpu.status.n())
@@ -295,12 +343,13 @@ func Test_BIT_SetZeroFlag(t *testing.T) {
 func Test_BIT_Absolute(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x2C, 0x05, 0x33, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b1100_1111
 	cpu.Bus.WriteMemory(0x33_05, 0b1111_0000)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.False(t, cpu.status.z())
 	assert.True(t, cpu.status.n())
@@ -310,13 +359,14 @@ func Test_BIT_Absolute(t *testing.T) {
 func Test_BMI_WhenSetNegative(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x30, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setN(true)
 	cpu.Bus.WriteMemory(0x03_12, 0xE8)
 	cpu.Bus.WriteMemory(0x03_13, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x03_14), cpu.ProgramCounter)
@@ -325,12 +375,13 @@ func Test_BMI_WhenSetNegative(t *testing.T) {
 func Test_BMI_WhenUnsetNegative(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x30, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setN(false)
 	cpu.Bus.WriteMemory(0x03_10, 0xE8)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x00), cpu.registerX)
 	assert.Equal(t, uint16(0x03_03), cpu.ProgramCounter)
@@ -339,13 +390,14 @@ func Test_BMI_WhenUnsetNegative(t *testing.T) {
 func Test_BMI_WhenMinusOperand(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x30, 0xF6, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setN(true)
 	cpu.Bus.WriteMemory(0x02_F8, 0xE8)
 	cpu.Bus.WriteMemory(0x02_F9, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x02_FA), cpu.ProgramCounter)
@@ -354,12 +406,13 @@ func Test_BMI_WhenMinusOperand(t *testing.T) {
 func Test_BNE_WhenSetZero(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xD0, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setZ(true)
 	cpu.Bus.WriteMemory(0x03_10, 0xE8)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x00), cpu.registerX)
 	assert.Equal(t, uint16(0x03_03), cpu.ProgramCounter)
@@ -368,13 +421,14 @@ func Test_BNE_WhenSetZero(t *testing.T) {
 func Test_BNE_WhenUnsetZero(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xD0, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setZ(false)
 	cpu.Bus.WriteMemory(0x03_12, 0xE8)
 	cpu.Bus.WriteMemory(0x03_13, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x03_14), cpu.ProgramCounter)
@@ -383,13 +437,14 @@ func Test_BNE_WhenUnsetZero(t *testing.T) {
 func Test_BNE_WhenMinusOperand(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xD0, 0xF6, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setZ(false)
 	cpu.Bus.WriteMemory(0x02_F8, 0xE8)
 	cpu.Bus.WriteMemory(0x02_F9, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x02_FA), cpu.ProgramCounter)
@@ -398,12 +453,13 @@ func Test_BNE_WhenMinusOperand(t *testing.T) {
 func Test_BPL_WhenSetNegative(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x10, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setN(true)
 	cpu.Bus.WriteMemory(0x03_10, 0xE8)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x00), cpu.registerX)
 	assert.Equal(t, uint16(0x03_03), cpu.ProgramCounter)
@@ -412,13 +468,14 @@ func Test_BPL_WhenSetNegative(t *testing.T) {
 func Test_BPL_WhenUnsetNegative(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x10, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setN(false)
 	cpu.Bus.WriteMemory(0x03_12, 0xE8)
 	cpu.Bus.WriteMemory(0x03_13, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x03_14), cpu.ProgramCounter)
@@ -427,13 +484,14 @@ func Test_BPL_WhenUnsetNegative(t *testing.T) {
 func Test_BPL_WhenMinusOperand(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x10, 0xF6, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setN(false)
 	cpu.Bus.WriteMemory(0x02_F8, 0xE8)
 	cpu.Bus.WriteMemory(0x02_F9, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x02_FA), cpu.ProgramCounter)
@@ -442,12 +500,13 @@ func Test_BPL_WhenMinusOperand(t *testing.T) {
 func Test_BVC_WhenSetOverflow(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x50, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setO(true)
 	cpu.Bus.WriteMemory(0x03_10, 0xE8)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x00), cpu.registerX)
 	assert.Equal(t, uint16(0x03_03), cpu.ProgramCounter)
@@ -456,13 +515,14 @@ func Test_BVC_WhenSetOverflow(t *testing.T) {
 func Test_BVC_WhenUnsetOverflow(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x50, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setO(false)
 	cpu.Bus.WriteMemory(0x03_12, 0xE8)
 	cpu.Bus.WriteMemory(0x03_13, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x03_14), cpu.ProgramCounter)
@@ -471,13 +531,14 @@ func Test_BVC_WhenUnsetOverflow(t *testing.T) {
 func Test_BVC_WhenMinusOperand(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x50, 0xF6, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setO(false)
 	cpu.Bus.WriteMemory(0x02_F8, 0xE8)
 	cpu.Bus.WriteMemory(0x02_F9, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x02_FA), cpu.ProgramCounter)
@@ -486,13 +547,14 @@ func Test_BVC_WhenMinusOperand(t *testing.T) {
 func Test_BVS_WhenSetOverflow(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x70, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setO(true)
 	cpu.Bus.WriteMemory(0x03_12, 0xE8)
 	cpu.Bus.WriteMemory(0x03_13, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x03_14), cpu.ProgramCounter)
@@ -501,12 +563,13 @@ func Test_BVS_WhenSetOverflow(t *testing.T) {
 func Test_BVS_WhenUnsetOverflow(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x70, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setO(false)
 	cpu.Bus.WriteMemory(0x03_10, 0xE8)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x00), cpu.registerX)
 	assert.Equal(t, uint16(0x03_03), cpu.ProgramCounter)
@@ -515,13 +578,14 @@ func Test_BVS_WhenUnsetOverflow(t *testing.T) {
 func Test_BVS_WhenMinusOperand(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x70, 0xF6, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setO(true)
 	cpu.Bus.WriteMemory(0x02_F8, 0xE8)
 	cpu.Bus.WriteMemory(0x02_F9, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x02_FA), cpu.ProgramCounter)
@@ -530,10 +594,11 @@ func Test_BVS_WhenMinusOperand(t *testing.T) {
 func Test_LDA_SetZeroFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xA9, 0x00, 0x00})
 	cpu.Reset(0x00_00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.z())
 }
@@ -541,10 +606,11 @@ func Test_LDA_SetZeroFlag(t *testing.T) {
 func Test_LDA_SetNegativeFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xA9, 0b1000_0000, 0x00})
 	cpu.Reset(0x00_00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.n())
 }
@@ -552,11 +618,12 @@ func Test_LDA_SetNegativeFlag(t *testing.T) {
 func Test_LDA_Immediate(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	// cpu.pc: 8000, 8001, 8002
 	cpu.loadForTest([]byte{0xA9, 0x05, 0x00})
 	cpu.Reset(0x00_00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x05), cpu.registerA)
 }
@@ -564,11 +631,12 @@ func Test_LDA_Immediate(t *testing.T) {
 func Test_LDA_ZeroPage(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xA5, 0x05, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x05, 0x11)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x11), cpu.registerA)
 }
@@ -576,12 +644,13 @@ func Test_LDA_ZeroPage(t *testing.T) {
 func Test_LDA_ZeroPageX(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xB5, 0x05, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerX = 0x01
 	cpu.Bus.WriteMemory(0x06, 0x11)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x11), cpu.registerA)
 }
@@ -589,11 +658,12 @@ func Test_LDA_ZeroPageX(t *testing.T) {
 func Test_LDA_Absolute(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xAD, 0x11, 0x12, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemoryUint16(0x12_11, 0x13)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x13), cpu.registerA)
 }
@@ -601,12 +671,13 @@ func Test_LDA_Absolute(t *testing.T) {
 func Test_LDA_AbsoluteX(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xBD, 0x11, 0x12, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerX = 0x01
 	cpu.Bus.WriteMemoryUint16(0x12_12, 0x13)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x13), cpu.registerA)
 }
@@ -614,12 +685,13 @@ func Test_LDA_AbsoluteX(t *testing.T) {
 func Test_LDA_AbsoluteY(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xB9, 0x11, 0x12, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerY = 0x01
 	cpu.Bus.WriteMemoryUint16(0x12_12, 0x13)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x13), cpu.registerA)
 }
@@ -627,13 +699,14 @@ func Test_LDA_AbsoluteY(t *testing.T) {
 func Test_LDA_IndirectX(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xA1, 0x11, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerX = 0x01
 	cpu.Bus.WriteMemoryUint16(0x12, 0x13_14)
 	cpu.Bus.WriteMemoryUint16(0x13_14, 0x05)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x05), cpu.registerA)
 }
@@ -641,14 +714,15 @@ func Test_LDA_IndirectX(t *testing.T) {
 func Test_LDA_IndirectY(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xB1, 0x11, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerY = 0x01
 	cpu.Bus.WriteMemory(0x11, 0x31)
 	cpu.Bus.WriteMemory(0x12, 0x32)
 	cpu.Bus.WriteMemory(0x32_32, 0x05)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x05), cpu.registerA)
 }
@@ -656,10 +730,11 @@ func Test_LDA_IndirectY(t *testing.T) {
 func Test_LDX_SetZeroFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xA2, 0x00, 0x00})
 	cpu.Reset(0x00_00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.z())
 }
@@ -667,10 +742,11 @@ func Test_LDX_SetZeroFlag(t *testing.T) {
 func Test_LDX_SetNegativeFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xA2, 0b1000_0000, 0x00})
 	cpu.Reset(0x00_00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.n())
 }
@@ -678,10 +754,11 @@ func Test_LDX_SetNegativeFlag(t *testing.T) {
 func Test_LDX_Immediate(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xA2, 0x05, 0x00})
 	cpu.Reset(0x00_00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x05), cpu.registerX)
 }
@@ -689,11 +766,12 @@ func Test_LDX_Immediate(t *testing.T) {
 func Test_LDX_ZeroPage(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xA6, 0x05, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x05, 0x11)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x11), cpu.registerX)
 }
@@ -701,12 +779,13 @@ func Test_LDX_ZeroPage(t *testing.T) {
 func Test_LDX_ZeroPageY(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xB6, 0x05, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerY = 0x01
 	cpu.Bus.WriteMemory(0x06, 0x11)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x11), cpu.registerX)
 }
@@ -714,11 +793,12 @@ func Test_LDX_ZeroPageY(t *testing.T) {
 func Test_LDX_Absolute(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xAE, 0x11, 0x12, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemoryUint16(0x12_11, 0x13)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x13), cpu.registerX)
 }
@@ -726,12 +806,13 @@ func Test_LDX_Absolute(t *testing.T) {
 func Test_LDX_AbsoluteY(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xBE, 0x11, 0x12, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerY = 0x01
 	cpu.Bus.WriteMemoryUint16(0x12_12, 0x13)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x13), cpu.registerX)
 }
@@ -739,10 +820,11 @@ func Test_LDX_AbsoluteY(t *testing.T) {
 func Test_LDY_SetZeroFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xA0, 0x00, 0x00})
 	cpu.Reset(0x00_00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.z())
 }
@@ -750,20 +832,22 @@ func Test_LDY_SetZeroFlag(t *testing.T) {
 func Test_LDY_SetNegativeFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xA0, 0b1000_0000, 0x00})
 	cpu.Reset(0x00_00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.n())
 }
 func Test_LDY_Immediate(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xA0, 0x05, 0x00})
 	cpu.Reset(0x00_00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x05), cpu.registerY)
 }
@@ -771,11 +855,12 @@ func Test_LDY_Immediate(t *testing.T) {
 func Test_LDY_ZeroPage(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xA4, 0x05, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x05, 0x11)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x11), cpu.registerY)
 }
@@ -783,12 +868,13 @@ func Test_LDY_ZeroPage(t *testing.T) {
 func Test_LDY_ZeroPageX(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xB4, 0x05, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerX = 0x01
 	cpu.Bus.WriteMemory(0x06, 0x11)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x11), cpu.registerY)
 }
@@ -796,11 +882,12 @@ func Test_LDY_ZeroPageX(t *testing.T) {
 func Test_LDY_Absolute(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xAC, 0x11, 0x12, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemoryUint16(0x12_11, 0x13)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x13), cpu.registerY)
 }
@@ -808,12 +895,13 @@ func Test_LDY_Absolute(t *testing.T) {
 func Test_LDY_AbsoluteX(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xBC, 0x11, 0x12, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerX = 0x01
 	cpu.Bus.WriteMemoryUint16(0x12_12, 0x13)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x13), cpu.registerY)
 }
@@ -821,11 +909,12 @@ func Test_LDY_AbsoluteX(t *testing.T) {
 func Test_LSR_LogicalShiftRight(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x4A, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b1001_0101
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
 	assert.False(t, cpu.status.z())
@@ -836,11 +925,12 @@ func Test_LSR_LogicalShiftRight(t *testing.T) {
 func Test_LSR_ShiftFromMemory(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x46, 0x05, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x05, 0b1001_0101)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
 	assert.False(t, cpu.status.z())
@@ -851,10 +941,11 @@ func Test_LSR_ShiftFromMemory(t *testing.T) {
 func Test_NOP_NoOperation(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xEA, 0xE8, 0x00})
 	cpu.Reset(0x00_00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, uint16(0x03_03), cpu.ProgramCounter)
 	assert.Equal(t, byte(0x01), cpu.registerX)
@@ -863,11 +954,12 @@ func Test_NOP_NoOperation(t *testing.T) {
 func Test_ORA_Accumulator(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x09, 0b1001_0110, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b0000_1111
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0b1001_1111), cpu.registerA)
 }
@@ -875,11 +967,12 @@ func Test_ORA_Accumulator(t *testing.T) {
 func Test_ORA_SetZeroFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x09, 0b0000_0000, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b0000_0000
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.z())
 	assert.False(t, cpu.status.n())
@@ -888,11 +981,12 @@ func Test_ORA_SetZeroFlag(t *testing.T) {
 func Test_ORA_SetNegativeFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x09, 0b1000_0000, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b1000_0000
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.False(t, cpu.status.z())
 	assert.True(t, cpu.status.n())
@@ -901,12 +995,13 @@ func Test_ORA_SetNegativeFlag(t *testing.T) {
 func Test_PHA_PushAccumulator(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x48, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.stackPointer = stackPointer(0x05)
 	cpu.registerA = 0x22
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x04), byte(cpu.stackPointer))
 	assert.Equal(t, byte(0x22), cpu.Bus.ReadMemory(0x01_05))
@@ -915,12 +1010,13 @@ func Test_PHA_PushAccumulator(t *testing.T) {
 func Test_PHP_PushStatus(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x08, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.stackPointer = stackPointer(0x05)
 	cpu.status = 0b1010_0110
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x04), byte(cpu.stackPointer))
 	assert.Equal(t, byte(0b1010_0110), cpu.Bus.ReadMemory(0x01_05))
@@ -929,12 +1025,13 @@ func Test_PHP_PushStatus(t *testing.T) {
 func Test_PLA_PopAccumulator(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x68, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.stackPointer = stackPointer(0x05)
 	cpu.Bus.WriteMemory(0x01_06, 0x22)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x06), byte(cpu.stackPointer))
 	assert.Equal(t, byte(0x22), cpu.registerA)
@@ -943,12 +1040,13 @@ func Test_PLA_PopAccumulator(t *testing.T) {
 func Test_PLA_SetNegativeFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x68, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.stackPointer = stackPointer(0x05)
 	cpu.Bus.WriteMemory(0x01_06, 0b1000_0000)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x06), byte(cpu.stackPointer))
 	assert.Equal(t, byte(0b1000_0000), cpu.registerA)
@@ -958,12 +1056,13 @@ func Test_PLA_SetNegativeFlag(t *testing.T) {
 func Test_PLP_PopStatus(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x28, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.stackPointer = stackPointer(0x05)
 	cpu.Bus.WriteMemory(0x01_06, 0b1010_0110)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x06), byte(cpu.stackPointer))
 	assert.Equal(t, byte(0b1010_0110), byte(cpu.status))
@@ -972,12 +1071,13 @@ func Test_PLP_PopStatus(t *testing.T) {
 func Test_ROL_Set0Bit(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x2A, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setC(true)
 	cpu.registerA = 0b1001_0101
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
 	assert.False(t, cpu.status.z())
@@ -988,12 +1088,13 @@ func Test_ROL_Set0Bit(t *testing.T) {
 func Test_ROL_Unset0Bit(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x2A, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setC(false)
 	cpu.registerA = 0b0101_1001
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.False(t, cpu.status.c())
 	assert.False(t, cpu.status.z())
@@ -1004,12 +1105,13 @@ func Test_ROL_Unset0Bit(t *testing.T) {
 func Test_ROL_RotateFromMemory(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x2E, 0x30, 0x04, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setC(true)
 	cpu.Bus.WriteMemory(0x04_30, 0b1001_0101)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
 	assert.False(t, cpu.status.z())
@@ -1020,12 +1122,13 @@ func Test_ROL_RotateFromMemory(t *testing.T) {
 func Test_ROR_Set7Bit(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x6A, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setC(true)
 	cpu.registerA = 0b1001_0101
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
 	assert.False(t, cpu.status.z())
@@ -1036,12 +1139,13 @@ func Test_ROR_Set7Bit(t *testing.T) {
 func Test_ROR_Unset7Bit(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x6A, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setC(false)
 	cpu.registerA = 0b0000_0001
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
 	assert.True(t, cpu.status.z())
@@ -1052,16 +1156,18 @@ func Test_ROR_Unset7Bit(t *testing.T) {
 func Test_ROR_RotateFromMemory(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x76, 0x30, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerX = 0x02
 	cpu.status.setC(true)
 	cpu.Bus.WriteMemory(0x32, 0b1001_0101)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
-	assert.True(t, cpu.status.z())
+	// Z は A ではなく書き込んだ値で決まる
+	assert.False(t, cpu.status.z())
 	assert.True(t, cpu.status.n())
 	assert.Equal(t, byte(0b1100_1010), cpu.Bus.ReadMemory(0x32))
 }
@@ -1069,17 +1175,18 @@ func Test_ROR_RotateFromMemory(t *testing.T) {
 func Test_RTI_ReturnFromInterrupt(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x40, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x01_FF, 0x05)
-	cpu.Bus.WriteMemory(0x01_FE, 0x06)
+	cpu.Bus.WriteMemory(0x01_FE, 0x07)
 	cpu.Bus.WriteMemory(0x01_FD, 0b1001_0110)
 	// SEC
 	cpu.Bus.WriteMemory(0x05_07, 0x38)
 	cpu.Bus.WriteMemory(0x05_08, 0x00)
 	cpu.stackPointer = stackPointer(0xFC)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	// SEC affected
 	assert.Equal(t, byte(0b1001_0111), byte(cpu.status))
@@ -1091,7 +1198,8 @@ func Test_RTI_ReturnFromInterrupt(t *testing.T) {
 func Test_RTS_PopStack(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x60, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x01_FF, 0x05)
@@ -1099,7 +1207,7 @@ func Test_RTS_PopStack(t *testing.T) {
 	cpu.Bus.WriteMemory(0x05_07, 0xE8)
 	cpu.Bus.WriteMemory(0x05_08, 0x00)
 	cpu.stackPointer = stackPointer(0xFD)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 	assert.Equal(t, uint16(0x05_09), cpu.ProgramCounter)
@@ -1109,11 +1217,12 @@ func Test_RTS_PopStack(t *testing.T) {
 func Test_SBC_Immediate(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xE9, 0b0111_1111, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b0111_1110
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	// 0b0111_1110 - 0b0111_1111
 	// 0b0111_1110 + 0b1000_0000 + 1
@@ -1133,11 +1242,12 @@ func Test_SBC_Immediate(t *testing.T) {
 func Test_SBC_SetCarryFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xE9, 0b0000_0010, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b0000_0011
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	// 0b0000_0011 - 0b0000_0010
 	// 0b0000_0011 + 0b1111_1101 + 1
@@ -1157,11 +1267,12 @@ func Test_SBC_SetCarryFlag(t *testing.T) {
 func Test_SBC_SetCarryAndOverflowFlags(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xE9, 0b0111_1111, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b1011_0000
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	// 0b1011_0000 - 0b0111_1111
 	// 0b1011_0000 + 0b1000_0000 + 1
@@ -1181,13 +1292,14 @@ func Test_SBC_SetCarryAndOverflowFlags(t *testing.T) {
 func Test_JSRandRTS(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x20, 0x30, 0x04, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x04_30, 0xE8)
 	cpu.Bus.WriteMemory(0x04_31, 0x60)
 	cpu.Bus.WriteMemory(0x04_32, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, uint16(0x03_04), cpu.ProgramCounter)
 	assert.Equal(t, byte(0x01), cpu.registerX)
@@ -1197,11 +1309,12 @@ func Test_JSRandRTS(t *testing.T) {
 func Test_TAX_MoveAtoX(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xAA, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0x10
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, cpu.registerX, byte(0x10))
 }
@@ -1209,11 +1322,12 @@ func Test_TAX_MoveAtoX(t *testing.T) {
 func Test_TAY_MoveAtoY(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xA8, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0x10
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, cpu.registerY, byte(0x10))
 }
@@ -1221,11 +1335,12 @@ func Test_TAY_MoveAtoY(t *testing.T) {
 func Test_TSX_MoveStoX(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xBA, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.stackPointer = 0x10
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, cpu.registerX, byte(0x10))
 }
@@ -1233,11 +1348,12 @@ func Test_TSX_MoveStoX(t *testing.T) {
 func Test_TXA_MoveXtoA(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x8A, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerX = 0x10
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, cpu.registerA, byte(0x10))
 }
@@ -1245,11 +1361,12 @@ func Test_TXA_MoveXtoA(t *testing.T) {
 func Test_TXS_MoveXtoS(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x9A, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerX = 0x10
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, cpu.stackPointer, stackPointer(0x10))
 }
@@ -1257,11 +1374,12 @@ func Test_TXS_MoveXtoS(t *testing.T) {
 func Test_TYA_MoveYtoA(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x98, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerY = 0x10
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, cpu.registerA, byte(0x10))
 }
@@ -1269,12 +1387,13 @@ func Test_TYA_MoveYtoA(t *testing.T) {
 func Test_CLC_UnsetCarryFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x18, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setC(true)
 	assert.True(t, cpu.status.c())
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.False(t, cpu.status.c())
 }
@@ -1282,12 +1401,13 @@ func Test_CLC_UnsetCarryFlag(t *testing.T) {
 func Test_CLD_UnsetDecimalFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xD8, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setD(true)
 	assert.True(t, cpu.status.d())
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.False(t, cpu.status.d())
 }
@@ -1295,12 +1415,13 @@ func Test_CLD_UnsetDecimalFlag(t *testing.T) {
 func Test_CLI_UnsetInterruptDisableFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x58, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setI(true)
 	assert.True(t, cpu.status.i())
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.False(t, cpu.status.i())
 }
@@ -1308,12 +1429,13 @@ func Test_CLI_UnsetInterruptDisableFlag(t *testing.T) {
 func Test_CLV_UnsetOverflowFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xB8, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.status.setO(true)
 	assert.True(t, cpu.status.o())
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.False(t, cpu.status.o())
 }
@@ -1321,11 +1443,12 @@ func Test_CLV_UnsetOverflowFlag(t *testing.T) {
 func Test_CMP_SetZeroAndCarry(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xC9, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0x10
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
 	assert.True(t, cpu.status.z())
@@ -1334,11 +1457,12 @@ func Test_CMP_SetZeroAndCarry(t *testing.T) {
 func Test_CMP_SetCarryOnly(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xC9, 0x09, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0x10
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
 	assert.False(t, cpu.status.z())
@@ -1347,11 +1471,12 @@ func Test_CMP_SetCarryOnly(t *testing.T) {
 func Test_CPX_SetZeroAndCarry(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xE0, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerX = 0x10
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
 	assert.True(t, cpu.status.z())
@@ -1360,11 +1485,12 @@ func Test_CPX_SetZeroAndCarry(t *testing.T) {
 func Test_CPX_SetCarryOnly(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xE0, 0x09, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerX = 0x10
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
 	assert.False(t, cpu.status.z())
@@ -1373,11 +1499,12 @@ func Test_CPX_SetCarryOnly(t *testing.T) {
 func Test_CPY_SetZeroAndCarry(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xC0, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerY = 0x10
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
 	assert.True(t, cpu.status.z())
@@ -1386,11 +1513,12 @@ func Test_CPY_SetZeroAndCarry(t *testing.T) {
 func Test_CPY_SetCarryOnly(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xC0, 0x09, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerY = 0x10
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
 	assert.False(t, cpu.status.z())
@@ -1399,11 +1527,12 @@ func Test_CPY_SetCarryOnly(t *testing.T) {
 func Test_DEC_SetZeroFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xC6, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x10, 0x01)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x00), cpu.Bus.ReadMemory(0x10))
 	assert.True(t, cpu.status.z())
@@ -1412,11 +1541,12 @@ func Test_DEC_SetZeroFlag(t *testing.T) {
 func Test_DEC_SetNegativeFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xC6, 0x01, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x01, 0b1000_0001)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0b1000_0000), cpu.Bus.ReadMemory(0x01))
 	assert.True(t, cpu.status.n())
@@ -1425,11 +1555,12 @@ func Test_DEC_SetNegativeFlag(t *testing.T) {
 func Test_DEC_Decrement(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xC6, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x10, 0x03)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x02), cpu.Bus.ReadMemory(0x10))
 }
@@ -1437,11 +1568,12 @@ func Test_DEC_Decrement(t *testing.T) {
 func Test_DEC_Underflow(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xC6, 0x01, 0xC6, 0x01, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x01, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0xFE), cpu.Bus.ReadMemory(0x01))
 }
@@ -1449,11 +1581,12 @@ func Test_DEC_Underflow(t *testing.T) {
 func Test_DEX_Decrement(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xCA, 0xCA, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerX = 0x03
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 }
@@ -1461,11 +1594,12 @@ func Test_DEX_Decrement(t *testing.T) {
 func Test_DEX_UnderflowX(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xCA, 0xCA, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerX = 0x00
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0xFE), cpu.registerX)
 }
@@ -1473,11 +1607,12 @@ func Test_DEX_UnderflowX(t *testing.T) {
 func Test_DEY_Decrement(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x88, 0x88, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerY = 0x03
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerY)
 }
@@ -1485,11 +1620,12 @@ func Test_DEY_Decrement(t *testing.T) {
 func Test_EOR_Accumulator(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x49, 0b1001_0110, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b0000_1111
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0b1001_1001), cpu.registerA)
 }
@@ -1497,11 +1633,12 @@ func Test_EOR_Accumulator(t *testing.T) {
 func Test_EOR_SetZeroFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x49, 0b0000_0000, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b0000_0000
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.z())
 	assert.False(t, cpu.status.n())
@@ -1510,11 +1647,12 @@ func Test_EOR_SetZeroFlag(t *testing.T) {
 func Test_EOR_SetNegativeFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x49, 0b0000_0000, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0b1000_0000
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.False(t, cpu.status.z())
 	assert.True(t, cpu.status.n())
@@ -1523,11 +1661,12 @@ func Test_EOR_SetNegativeFlag(t *testing.T) {
 func Test_DEY_UnderflowY(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x88, 0x88, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerY = 0x00
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0xFE), cpu.registerY)
 }
@@ -1535,11 +1674,12 @@ func Test_DEY_UnderflowY(t *testing.T) {
 func Test_INC_SetZeroFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xE6, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x10, 0xFF)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x00), cpu.Bus.ReadMemory(0x10))
 	assert.True(t, cpu.status.z())
@@ -1548,11 +1688,12 @@ func Test_INC_SetZeroFlag(t *testing.T) {
 func Test_INC_SetNegativeFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xE6, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x10, 0b0111_1111)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x80), cpu.Bus.ReadMemory(0x10))
 	assert.True(t, cpu.status.n())
@@ -1561,11 +1702,12 @@ func Test_INC_SetNegativeFlag(t *testing.T) {
 func Test_INC_Increment(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xE6, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x10, 0x02)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x03), cpu.Bus.ReadMemory(0x10))
 }
@@ -1573,11 +1715,12 @@ func Test_INC_Increment(t *testing.T) {
 func Test_INC_Overflow(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xE6, 0x10, 0xE6, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x10, 0xFF)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.Bus.ReadMemory(0x10))
 }
@@ -1585,11 +1728,12 @@ func Test_INC_Overflow(t *testing.T) {
 func Test_INX_Increment(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xE8, 0xE8, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerX = 0x01
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x03), cpu.registerX)
 }
@@ -1597,11 +1741,12 @@ func Test_INX_Increment(t *testing.T) {
 func Test_INX_OverflowX(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xE8, 0xE8, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerX = 0xFF
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerX)
 }
@@ -1609,11 +1754,12 @@ func Test_INX_OverflowX(t *testing.T) {
 func Test_INY_Increment(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xC8, 0xC8, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerY = 0x01
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x03), cpu.registerY)
 }
@@ -1621,11 +1767,12 @@ func Test_INY_Increment(t *testing.T) {
 func Test_INY_OverflowY(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xC8, 0xC8, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerY = 0xFF
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x01), cpu.registerY)
 }
@@ -1633,12 +1780,13 @@ func Test_INY_OverflowY(t *testing.T) {
 func Test_JMP_Absolute(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x4C, 0x30, 0x04, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x04_30, 0xE8)
 	cpu.Bus.WriteMemory(0x04_31, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, uint16(0x04_32), cpu.ProgramCounter)
 	assert.Equal(t, byte(0x01), cpu.registerX)
@@ -1647,28 +1795,46 @@ func Test_JMP_Absolute(t *testing.T) {
 func Test_JMP_Indirect(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x6C, 0x30, 0x04, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x04_30, 0x44)
 	cpu.Bus.WriteMemory(0x04_31, 0x05)
 	cpu.Bus.WriteMemory(0x05_44, 0xE8)
 	cpu.Bus.WriteMemory(0x05_45, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, uint16(0x05_46), cpu.ProgramCounter)
 	assert.Equal(t, byte(0x01), cpu.registerX)
 }
 
+func Test_JMP_IndirectPageBoundaryBug(t *testing.T) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(validrom)
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.loadForTest([]byte{0x6C, 0xFF, 0x04})
+	cpu.Reset(0x00_00)
+	cpu.Bus.WriteMemory(0x04_FF, 0x44)
+	// 上位バイトは 0x0500 ではなく 0x0400 から読む
+	cpu.Bus.WriteMemory(0x04_00, 0x05)
+	cpu.Bus.WriteMemory(0x05_00, 0x06)
+	_, err := cpu.Step()
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(0x05_44), cpu.ProgramCounter)
+}
+
 func Test_JSR_PushStack(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x20, 0x30, 0x04, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.Bus.WriteMemory(0x04_30, 0xE8)
 	cpu.Bus.WriteMemory(0x04_31, 0x00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, uint16(0x04_32), cpu.ProgramCounter)
 	assert.Equal(t, byte(0x01), cpu.registerX)
@@ -1679,11 +1845,12 @@ func Test_JSR_PushStack(t *testing.T) {
 func Test_STA_Immediate(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x85, 0x01, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerA = 0x05
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x05), cpu.Bus.ReadMemory(0x01))
 }
@@ -1691,10 +1858,11 @@ func Test_STA_Immediate(t *testing.T) {
 func Test_SEC_SetCarryFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x38, 0x00})
 	cpu.Reset(0x00_00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.c())
 }
@@ -1702,10 +1870,11 @@ func Test_SEC_SetCarryFlag(t *testing.T) {
 func Test_SED_SetDecimalFlag(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xF8, 0x00})
 	cpu.Reset(0x00_00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.d())
 }
@@ -1713,10 +1882,11 @@ func Test_SED_SetDecimalFlag(t *testing.T) {
 func Test_SEI_SetInterruptDisable(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x78, 0x00})
 	cpu.Reset(0x00_00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.True(t, cpu.status.i())
 }
@@ -1724,11 +1894,12 @@ func Test_SEI_SetInterruptDisable(t *testing.T) {
 func Test_STX_Immediate(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x86, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerX = 0x05
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x05), cpu.Bus.ReadMemory(0x10))
 }
@@ -1736,11 +1907,12 @@ func Test_STX_Immediate(t *testing.T) {
 func Test_STY_Immediate(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0x84, 0x10, 0x00})
 	cpu.Reset(0x00_00)
 	cpu.registerY = 0x05
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0x05), cpu.Bus.ReadMemory(0x10))
 }
@@ -1748,10 +1920,847 @@ func Test_STY_Immediate(t *testing.T) {
 func Test_5OpsWorkingTogether(t *testing.T) {
 	memory := memory.NewMemory()
 	rom, _ := rom.NewROM(validrom)
-	cpu := NewCPU(bus.NewBus(&memory, rom))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
 	cpu.loadForTest([]byte{0xa9, 0xc0, 0xaa, 0xe8, 0x00})
 	cpu.Reset(0x00_00)
-	cpu.Run()
+	cpu.Run(context.Background())
 
 	assert.Equal(t, byte(0xC1), cpu.registerX)
 }
+
+func Test_NMI_PushStackAndJumpToVector(t *testing.T) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(romWithVectorsForTest(0x04_00, 0x00_00))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
+	// LDA #$80; STA $2000; JMP $0305
+	cpu.loadForTest([]byte{0xA9, 0x80, 0x8D, 0x00, 0x20, 0x4C, 0x05, 0x03})
+	cpu.Reset(0x00_00)
+	cpu.Bus.WriteMemory(0x04_00, 0x00)
+	cpu.Run(context.Background())
+
+	assert.Equal(t, uint16(0x04_01), cpu.ProgramCounter)
+	assert.True(t, cpu.status.i())
+	assert.Equal(t, byte(0x03), cpu.Bus.ReadMemory(0x01_FF))
+	assert.Equal(t, byte(0x05), cpu.Bus.ReadMemory(0x01_FE))
+	// B は下がり、bit 5 は立つ
+	assert.Equal(t, byte(0b1010_0000), cpu.Bus.ReadMemory(0x01_FD))
+}
+
+func Test_NMI_ReturnWithRTI(t *testing.T) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(romWithVectorsForTest(0x04_00, 0x00_00))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
+	// LDA #$80; STA $2000; CPX #$01; BNE -4; BRK
+	cpu.loadForTest([]byte{0xA9, 0x80, 0x8D, 0x00, 0x20, 0xE0, 0x01, 0xD0, 0xFC, 0x00})
+	cpu.Reset(0x00_00)
+	// INX; RTI
+	cpu.Bus.WriteMemory(0x04_00, 0xE8)
+	cpu.Bus.WriteMemory(0x04_01, 0x40)
+	cpu.Run(context.Background())
+
+	assert.Equal(t, uint16(0x03_0A), cpu.ProgramCounter)
+	assert.Equal(t, byte(0x01), cpu.registerX)
+	assert.Equal(t, byte(0xFF), byte(cpu.stackPointer))
+}
+
+func Test_BRK_PushStackAndJumpToVector(t *testing.T) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(romWithVectorsForTest(0x00_00, 0x04_00))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	// BRK; (padding); INX; (unknown opcode)
+	cpu.loadForTest([]byte{0x00, 0xFF, 0xE8, 0x02})
+	cpu.Reset(0x00_00)
+	cpu.status.setC(true)
+	// INY; RTI
+	cpu.Bus.WriteMemory(0x04_00, 0xC8)
+	cpu.Bus.WriteMemory(0x04_01, 0x40)
+	cpu.Run(context.Background())
+
+	assert.Equal(t, byte(0x01), cpu.registerY)
+	assert.Equal(t, byte(0x01), cpu.registerX)
+	// BRK の 2 バイト後が戻り先になる
+	assert.Equal(t, byte(0x03), cpu.Bus.ReadMemory(0x01_FF))
+	assert.Equal(t, byte(0x02), cpu.Bus.ReadMemory(0x01_FE))
+	// B と bit 5 が立つ
+	assert.Equal(t, byte(0b0011_0001), cpu.Bus.ReadMemory(0x01_FD))
+	// RTI でスタックのステータスが戻るため I は下りている
+	assert.False(t, cpu.status.i())
+}
+
+func Test_IRQ_JumpToVector(t *testing.T) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(romWithVectorsForTest(0x00_00, 0x04_00))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
+	cpu.loadForTest([]byte{0xE8, 0x00})
+	cpu.Reset(0x00_00)
+	cpu.Bus.WriteMemory(0x04_00, 0x00)
+	cpu.Bus.SetIRQ(bus.IRQFrameCounter, true)
+	cpu.Run(context.Background())
+
+	assert.Equal(t, uint16(0x04_01), cpu.ProgramCounter)
+	assert.Equal(t, byte(0x00), cpu.registerX)
+	assert.True(t, cpu.status.i())
+	assert.Equal(t, byte(0x03), cpu.Bus.ReadMemory(0x01_FF))
+	assert.Equal(t, byte(0x00), cpu.Bus.ReadMemory(0x01_FE))
+	// B は下がり、bit 5 は立つ
+	assert.Equal(t, byte(0b0010_0000), cpu.Bus.ReadMemory(0x01_FD))
+}
+
+func Test_IRQ_IgnoredWhenInterruptDisabled(t *testing.T) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(romWithVectorsForTest(0x00_00, 0x04_00))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
+	cpu.loadForTest([]byte{0xE8, 0x00})
+	cpu.Reset(0x00_00)
+	cpu.status.setI(true)
+	cpu.Bus.SetIRQ(bus.IRQFrameCounter, true)
+	cpu.Run(context.Background())
+
+	assert.Equal(t, uint16(0x03_02), cpu.ProgramCounter)
+	assert.Equal(t, byte(0x01), cpu.registerX)
+}
+
+func Test_IRQ_LevelTriggered(t *testing.T) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(romWithVectorsForTest(0x00_00, 0x04_00))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
+	// CLI; INX; BRK
+	cpu.loadForTest([]byte{0x58, 0xE8, 0x00})
+	cpu.Reset(0x00_00)
+	cpu.status.setI(true)
+	// INY; CPY #$02; BNE +1; BRK; RTI
+	cpu.Bus.CopyToMemory(0x04_00, []byte{0xC8, 0xC0, 0x02, 0xD0, 0x01, 0x00, 0x40})
+	cpu.Bus.SetIRQ(bus.IRQFrameCounter, true)
+	cpu.Run(context.Background())
+
+	// アサートされたままなので RTI の直後に再び割り込む
+	assert.Equal(t, byte(0x02), cpu.registerY)
+	assert.Equal(t, byte(0x00), cpu.registerX)
+	assert.Equal(t, uint16(0x04_06), cpu.ProgramCounter)
+}
+
+func Test_IRQ_MMC3ScanlineCounter(t *testing.T) {
+	memory := memory.NewMemory()
+	raw := romWithVectorsForTest(0x00_00, 0x04_00)
+	// マッパー 4 (MMC3)
+	raw[6] = 0b0100_0001
+	rom, _ := rom.NewROM(raw)
+	b, err := bus.NewBus(&memory, rom)
+	assert.NoError(t, err)
+	cpu := NewCPU(b)
+	cpu.HaltOnBRK = true
+	cpu.loadForTest([]byte{
+		0xA9, 0x08, 0x8D, 0x00, 0x20, // LDA #$08; STA $2000 (スプライトは 0x1000 のパターンテーブル)
+		0x8D, 0x01, 0x20, // STA $2001 (背景を表示)
+		0xA9, 0x0A, 0x8D, 0x00, 0xC0, // LDA #10; STA $C000 (IRQ latch)
+		0x8D, 0x01, 0xC0, // STA $C001 (IRQ reload)
+		0x8D, 0x01, 0xE0, // STA $E001 (IRQ enable)
+		0x58,             // CLI
+		0x4C, 0x14, 0x03, // JMP *
+	})
+	cpu.Reset(0x00_00)
+	cpu.Bus.WriteMemory(0x04_00, 0x00)
+
+	err = cpu.RunFor(NTSCClockRate / 60)
+
+	// ライン 0 でカウンタに 10 を読み込み、10 ライン後に IRQ が出る
+	assert.ErrorIs(t, err, ErrBRK)
+	scanline, _ := b.PPU.Position()
+	assert.Equal(t, 10, scanline)
+	assert.True(t, cpu.status.i())
+}
+
+func Test_Cycles(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []byte
+		setup   func(cpu *CPU)
+		want    uint64
+	}{
+		{name: "AbsoluteX", program: []byte{0xBD, 0x00, 0x02}, setup: func(cpu *CPU) { cpu.registerX = 0x01 }, want: 4},
+		{name: "AbsoluteX/PageCrossed", program: []byte{0xBD, 0xFF, 0x02}, setup: func(cpu *CPU) { cpu.registerX = 0x01 }, want: 5},
+		{name: "AbsoluteY/PageCrossed", program: []byte{0xB9, 0xFF, 0x02}, setup: func(cpu *CPU) { cpu.registerY = 0x01 }, want: 5},
+		// 書き込み命令はページをまたいでも増えない
+		{name: "STA/AbsoluteX/PageCrossed", program: []byte{0x9D, 0xFF, 0x02}, setup: func(cpu *CPU) { cpu.registerX = 0x01 }, want: 5},
+		{name: "IndirectY/PageCrossed", program: []byte{0xB1, 0x10}, setup: func(cpu *CPU) {
+			cpu.Bus.WriteMemoryUint16(0x10, 0x02_FF)
+			cpu.registerY = 0x01
+		}, want: 6},
+		{name: "Branch/NotTaken", program: []byte{0xD0, 0x00}, setup: func(cpu *CPU) { cpu.status.setZ(true) }, want: 2},
+		{name: "Branch/Taken", program: []byte{0xD0, 0x00}, setup: func(cpu *CPU) {}, want: 3},
+		{name: "Branch/TakenToNewPage", program: []byte{0xD0, 0x80}, setup: func(cpu *CPU) {}, want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := memory.NewMemory()
+			rom, _ := rom.NewROM(validrom)
+			cpu := NewCPU(newBusForTest(t, &memory, rom))
+			cpu.HaltOnBRK = true
+			cpu.loadForTest(append(tt.program, 0x00))
+			cpu.Reset(0x00_00)
+			tt.setup(&cpu)
+			cpu.Run(context.Background())
+
+			// リセットと最後の BRK の 7 サイクルずつを除く
+			assert.Equal(t, tt.want, cpu.Cycles()-7-7)
+		})
+	}
+}
+
+func Test_Step_ReturnCycles(t *testing.T) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(validrom)
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	// LDA #$01; LDA $0200,X
+	cpu.loadForTest([]byte{0xA9, 0x01, 0xBD, 0xFF, 0x02})
+	cpu.Reset(0x00_00)
+	cpu.registerX = 0x01
+
+	cycles, err := cpu.Step()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), cycles)
+	assert.Equal(t, uint16(0x03_02), cpu.ProgramCounter)
+
+	cycles, err = cpu.Step()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), cycles)
+	assert.Equal(t, uint16(0x03_05), cpu.ProgramCounter)
+}
+
+func Test_Step_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []byte
+		setup   func(cpu *CPU)
+		check   func(t *testing.T, err error)
+		wantPC  uint16
+	}{
+		{
+			name:    "UnknownOpcode",
+			program: []byte{0xEA},
+			setup:   func(cpu *CPU) { cpu.Instructions[0xEA] = instruction{} },
+			check: func(t *testing.T, err error) {
+				var target *UnknownOpcodeError
+				assert.ErrorAs(t, err, &target)
+				assert.Equal(t, &UnknownOpcodeError{Opcode: 0xEA, Address: 0x03_00}, target)
+			},
+			wantPC: 0x03_00,
+		},
+		{
+			name:    "Jam",
+			program: []byte{0x02},
+			setup:   func(cpu *CPU) {},
+			check: func(t *testing.T, err error) {
+				var target *JamError
+				assert.ErrorAs(t, err, &target)
+				assert.Equal(t, &JamError{Opcode: 0x02, Address: 0x03_00}, target)
+			},
+			wantPC: 0x03_00,
+		},
+		{
+			name:    "HaltOnBRK",
+			program: []byte{0x00},
+			setup:   func(cpu *CPU) { cpu.HaltOnBRK = true },
+			check: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, ErrBRK)
+			},
+			wantPC: 0x03_01,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := memory.NewMemory()
+			rom, _ := rom.NewROM(validrom)
+			cpu := NewCPU(newBusForTest(t, &memory, rom))
+			cpu.loadForTest(tt.program)
+			cpu.Reset(0x00_00)
+			tt.setup(&cpu)
+
+			_, err := cpu.Step()
+			tt.check(t, err)
+			assert.Equal(t, tt.wantPC, cpu.ProgramCounter)
+		})
+	}
+}
+
+func Test_RunFor(t *testing.T) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(validrom)
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	// INX; JMP $0300
+	cpu.loadForTest([]byte{0xE8, 0x4C, 0x00, 0x03})
+	cpu.Reset(0x00_00)
+
+	assert.NoError(t, cpu.RunFor(50))
+	// INX と JMP の 1 回で 5 サイクル
+	assert.Equal(t, byte(10), cpu.registerX)
+	assert.Equal(t, uint64(7+50), cpu.Cycles())
+}
+
+func Test_RunFrame(t *testing.T) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(validrom)
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	// JMP $0300
+	cpu.loadForTest([]byte{0x4C, 0x00, 0x03})
+	cpu.Reset(0x00_00)
+
+	assert.NoError(t, cpu.RunFrame())
+	assert.Equal(t, uint64(1), cpu.Bus.FrameCount())
+	first := cpu.Cycles()
+	// 241 ライン目の 1 ドット目で vblank に入る
+	assert.InDelta(t, (241*341+2)/3, first, 3)
+
+	assert.NoError(t, cpu.RunFrame())
+	assert.Equal(t, uint64(2), cpu.Bus.FrameCount())
+	// 描画が無効なので 1 フレームは 341 * 262 / 3 サイクル
+	assert.InDelta(t, 341*262/3, cpu.Cycles()-first, 3)
+}
+
+func Test_RunFrame_StopOnError(t *testing.T) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(validrom)
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.loadForTest([]byte{0xE8, 0x02})
+	cpu.Reset(0x00_00)
+
+	var target *JamError
+	assert.ErrorAs(t, cpu.RunFrame(), &target)
+	assert.Equal(t, byte(0x01), cpu.registerX)
+}
+
+func Test_Run_ReturnStopReason(t *testing.T) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(validrom)
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.HaltOnBRK = true
+	cpu.loadForTest([]byte{0xE8, 0x00})
+	cpu.Reset(0x00_00)
+
+	assert.ErrorIs(t, cpu.Run(context.Background()), ErrBRK)
+}
+
+func Test_Run_Cancel(t *testing.T) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(validrom)
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	// JMP $0300
+	cpu.loadForTest([]byte{0x4C, 0x00, 0x03})
+	cpu.Reset(0x00_00)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, cpu.Run(ctx), context.DeadlineExceeded)
+	assert.Greater(t, cpu.Cycles(), uint64(7))
+}
+
+func Test_Run_PauseAndResume(t *testing.T) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(validrom)
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	// JMP $0300
+	cpu.loadForTest([]byte{0x4C, 0x00, 0x03})
+	cpu.Reset(0x00_00)
+	cpu.Pause()
+	assert.True(t, cpu.Paused())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- cpu.Run(ctx) }()
+
+	// 一時停止中は命令を実行しない
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, uint64(7), cpu.Cycles())
+
+	cpu.Resume()
+	assert.False(t, cpu.Paused())
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Greater(t, cpu.Cycles(), uint64(7))
+}
+
+func Test_Run_CancelWhilePaused(t *testing.T) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(validrom)
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.loadForTest([]byte{0x4C, 0x00, 0x03})
+	cpu.Reset(0x00_00)
+	cpu.Pause()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, cpu.Run(ctx), context.DeadlineExceeded)
+	assert.Equal(t, uint64(7), cpu.Cycles())
+}
+
+func Benchmark_Step(b *testing.B) {
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(validrom)
+	cpu := NewCPU(newBusForTest(b, &memory, rom))
+	// LDX #$00; loop: LDA $0200,X; ADC #$01; STA $0200,X; INX; BNE loop; JMP $0300
+	cpu.loadForTest([]byte{
+		0xA2, 0x00,
+		0xBD, 0x00, 0x02,
+		0x69, 0x01,
+		0x9D, 0x00, 0x02,
+		0xE8,
+		0xD0, 0xF5,
+		0x4C, 0x00, 0x03,
+	})
+	cpu.Reset(0x00_00)
+
+	b.ResetTimer()
+	for range b.N {
+		if _, err := cpu.Step(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "instructions/s")
+}
+
+func Test_Unofficial(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []byte
+		setup   func(cpu *CPU)
+		check   func(t *testing.T, cpu *CPU)
+		cycles  uint64
+	}{
+		{
+			name:    "NOP/Implied",
+			program: []byte{0x1A},
+			setup:   func(cpu *CPU) {},
+			check:   func(t *testing.T, cpu *CPU) { assert.Equal(t, uint16(0x03_01), cpu.ProgramCounter) },
+			cycles:  2,
+		},
+		{
+			name:    "NOP/AbsoluteX/PageCrossed",
+			program: []byte{0x1C, 0xFF, 0x02},
+			setup:   func(cpu *CPU) { cpu.registerX = 0x01 },
+			check:   func(t *testing.T, cpu *CPU) { assert.Equal(t, uint16(0x03_03), cpu.ProgramCounter) },
+			cycles:  5,
+		},
+		{
+			name:    "LAX/ZeroPage",
+			program: []byte{0xA7, 0x10},
+			setup:   func(cpu *CPU) { cpu.Bus.WriteMemory(0x10, 0x80) },
+			check: func(t *testing.T, cpu *CPU) {
+				assert.Equal(t, byte(0x80), cpu.registerA)
+				assert.Equal(t, byte(0x80), cpu.registerX)
+				assert.True(t, cpu.status.n())
+			},
+			cycles: 3,
+		},
+		{
+			name:    "SAX/ZeroPage",
+			program: []byte{0x87, 0x10},
+			setup: func(cpu *CPU) {
+				cpu.registerA = 0b1100_1100
+				cpu.registerX = 0b1010_1010
+			},
+			check:  func(t *testing.T, cpu *CPU) { assert.Equal(t, byte(0b1000_1000), cpu.Bus.ReadMemory(0x10)) },
+			cycles: 3,
+		},
+		{
+			name:    "SBC/Immediate",
+			program: []byte{0xEB, 0x01},
+			setup: func(cpu *CPU) {
+				cpu.registerA = 0x05
+				cpu.status.setC(true)
+			},
+			check: func(t *testing.T, cpu *CPU) {
+				assert.Equal(t, byte(0x04), cpu.registerA)
+				assert.True(t, cpu.status.c())
+			},
+			cycles: 2,
+		},
+		{
+			name:    "DCP/ZeroPage",
+			program: []byte{0xC7, 0x10},
+			setup: func(cpu *CPU) {
+				cpu.Bus.WriteMemory(0x10, 0x06)
+				cpu.registerA = 0x05
+			},
+			check: func(t *testing.T, cpu *CPU) {
+				assert.Equal(t, byte(0x05), cpu.Bus.ReadMemory(0x10))
+				assert.True(t, cpu.status.z())
+				assert.True(t, cpu.status.c())
+			},
+			cycles: 5,
+		},
+		{
+			name:    "ISB/Absolute",
+			program: []byte{0xEF, 0x00, 0x02},
+			setup: func(cpu *CPU) {
+				cpu.Bus.WriteMemory(0x02_00, 0x01)
+				cpu.registerA = 0x01
+				cpu.status.setC(true)
+			},
+			check: func(t *testing.T, cpu *CPU) {
+				assert.Equal(t, byte(0x02), cpu.Bus.ReadMemory(0x02_00))
+				assert.Equal(t, byte(0xFF), cpu.registerA)
+				assert.False(t, cpu.status.c())
+				assert.True(t, cpu.status.n())
+			},
+			cycles: 6,
+		},
+		{
+			name:    "SLO/IndirectY",
+			program: []byte{0x13, 0x10},
+			setup: func(cpu *CPU) {
+				cpu.Bus.WriteMemoryUint16(0x10, 0x04_FF)
+				cpu.Bus.WriteMemory(0x05_00, 0b1000_0011)
+				cpu.registerY = 0x01
+				cpu.registerA = 0b0000_0001
+			},
+			check: func(t *testing.T, cpu *CPU) {
+				assert.Equal(t, byte(0b0000_0110), cpu.Bus.ReadMemory(0x05_00))
+				assert.Equal(t, byte(0b0000_0111), cpu.registerA)
+				assert.True(t, cpu.status.c())
+			},
+			// 読み書きする非公式命令はページをまたいでもサイクルが増えない
+			cycles: 8,
+		},
+		{
+			name:    "RLA/ZeroPage",
+			program: []byte{0x27, 0x10},
+			setup: func(cpu *CPU) {
+				cpu.Bus.WriteMemory(0x10, 0b1000_0001)
+				cpu.registerA = 0b0000_0011
+				cpu.status.setC(true)
+			},
+			check: func(t *testing.T, cpu *CPU) {
+				assert.Equal(t, byte(0b0000_0011), cpu.Bus.ReadMemory(0x10))
+				assert.Equal(t, byte(0b0000_0011), cpu.registerA)
+				assert.True(t, cpu.status.c())
+			},
+			cycles: 5,
+		},
+		{
+			name:    "SRE/ZeroPageX",
+			program: []byte{0x57, 0x0F},
+			setup: func(cpu *CPU) {
+				cpu.Bus.WriteMemory(0x10, 0b0000_0011)
+				cpu.registerX = 0x01
+				cpu.registerA = 0b0000_0001
+			},
+			check: func(t *testing.T, cpu *CPU) {
+				assert.Equal(t, byte(0b0000_0001), cpu.Bus.ReadMemory(0x10))
+				assert.Equal(t, byte(0x00), cpu.registerA)
+				assert.True(t, cpu.status.z())
+				assert.True(t, cpu.status.c())
+			},
+			cycles: 6,
+		},
+		{
+			name:    "RRA/ZeroPage",
+			program: []byte{0x67, 0x10},
+			setup: func(cpu *CPU) {
+				cpu.Bus.WriteMemory(0x10, 0b0000_0011)
+				cpu.registerA = 0x10
+			},
+			check: func(t *testing.T, cpu *CPU) {
+				assert.Equal(t, byte(0b0000_0001), cpu.Bus.ReadMemory(0x10))
+				// ROR で押し出されたキャリーが加わる
+				assert.Equal(t, byte(0x12), cpu.registerA)
+				assert.False(t, cpu.status.c())
+			},
+			cycles: 5,
+		},
+		{
+			name:    "ANC/Immediate",
+			program: []byte{0x0B, 0x80},
+			setup:   func(cpu *CPU) { cpu.registerA = 0xFF },
+			check: func(t *testing.T, cpu *CPU) {
+				assert.Equal(t, byte(0x80), cpu.registerA)
+				assert.True(t, cpu.status.c())
+				assert.True(t, cpu.status.n())
+			},
+			cycles: 2,
+		},
+		{
+			name:    "ALR/Immediate",
+			program: []byte{0x4B, 0x03},
+			setup:   func(cpu *CPU) { cpu.registerA = 0xFF },
+			check: func(t *testing.T, cpu *CPU) {
+				assert.Equal(t, byte(0x01), cpu.registerA)
+				assert.True(t, cpu.status.c())
+			},
+			cycles: 2,
+		},
+		{
+			name:    "ARR/Immediate",
+			program: []byte{0x6B, 0xC0},
+			setup: func(cpu *CPU) {
+				cpu.registerA = 0xFF
+				cpu.status.setC(true)
+			},
+			check: func(t *testing.T, cpu *CPU) {
+				assert.Equal(t, byte(0xE0), cpu.registerA)
+				assert.True(t, cpu.status.c())
+				assert.False(t, cpu.status.o())
+			},
+			cycles: 2,
+		},
+		{
+			name:    "SBX/Immediate",
+			program: []byte{0xCB, 0x02},
+			setup: func(cpu *CPU) {
+				cpu.registerA = 0x0F
+				cpu.registerX = 0x03
+			},
+			check: func(t *testing.T, cpu *CPU) {
+				assert.Equal(t, byte(0x01), cpu.registerX)
+				assert.True(t, cpu.status.c())
+			},
+			cycles: 2,
+		},
+		{
+			name:    "LAS/AbsoluteY",
+			program: []byte{0xBB, 0x00, 0x02},
+			setup:   func(cpu *CPU) { cpu.Bus.WriteMemory(0x02_00, 0xF3) },
+			check: func(t *testing.T, cpu *CPU) {
+				assert.Equal(t, byte(0xF3), cpu.registerA)
+				assert.Equal(t, byte(0xF3), cpu.registerX)
+				assert.Equal(t, byte(0xF3), byte(cpu.stackPointer))
+			},
+			cycles: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := memory.NewMemory()
+			rom, _ := rom.NewROM(validrom)
+			cpu := NewCPU(newBusForTest(t, &memory, rom))
+			cpu.loadForTest(tt.program)
+			cpu.Reset(0x00_00)
+			tt.setup(&cpu)
+
+			cycles, err := cpu.Step()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.cycles, cycles)
+			tt.check(t, &cpu)
+		})
+	}
+}
+
+func Test_Trace_Nestest(t *testing.T) {
+	raw := append([]byte{}, validrom...)
+	raw[4] = 0x01
+	prg := make([]byte, 0x40_00)
+	copy(prg[0x00_00:], []byte{0x4C, 0xF5, 0xC5})
+	copy(prg[0x05_F5:], []byte{0xA2, 0x00, 0x86, 0x00, 0x86, 0x10, 0x86, 0x11, 0x20, 0x2D, 0xC7})
+	copy(prg[0x07_2D:], []byte{0xEA})
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM(append(raw, prg...))
+	cpu := NewCPU(newBusForTest(t, &memory, rom))
+	cpu.Reset(0x00_00)
+	// nestest を自動実行するときの初期状態
+	cpu.ProgramCounter = 0xC0_00
+	cpu.status = status(0x24)
+	cpu.stackPointer = stackPointer(0xFD)
+
+	var trace strings.Builder
+	cpu.Tracer = &trace
+	for range 7 {
+		_, err := cpu.Step()
+		assert.NoError(t, err)
+	}
+
+	want := strings.Join([]string{
+		"C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7",
+		"C5F5  A2 00     LDX #$00                        A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 30 CYC:10",
+		"C5F7  86 00     STX $00 = 00                    A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 36 CYC:12",
+		"C5F9  86 10     STX $10 = 00                    A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 45 CYC:15",
+		"C5FB  86 11     STX $11 = 00                    A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 54 CYC:18",
+		"C5FD  20 2D C7  JSR $C72D                       A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 63 CYC:21",
+		"C72D  EA        NOP                             A:00 X:00 Y:00 P:26 SP:FB PPU:  0, 81 CYC:27",
+	}, "\n") + "\n"
+	assert.Equal(t, want, trace.String())
+}
+
+func Test_Trace_Operands(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []byte
+		want    string
+	}{
+		{name: "Accumulator", program: []byte{0x0A}, want: " ASL A"},
+		{name: "ZeroPageX", program: []byte{0xB5, 0x10}, want: " LDA $10,X @ 11 = 05"},
+		{name: "Absolute", program: []byte{0xAD, 0x00, 0x04}, want: " LDA $0400 = 07"},
+		{name: "AbsoluteY", program: []byte{0xB9, 0x00, 0x04}, want: " LDA $0400,Y @ 0401 = 08"},
+		{name: "Indirect", program: []byte{0x6C, 0x00, 0x04}, want: " JMP ($0400) = 0807"},
+		{name: "IndirectX", program: []byte{0xA1, 0x11}, want: " LDA ($11,X) @ 12 = 0400 = 07"},
+		{name: "IndirectY", program: []byte{0xB1, 0x12}, want: " LDA ($12),Y = 0400 @ 0401 = 08"},
+		{name: "Relative", program: []byte{0xD0, 0x03}, want: " BNE $0305"},
+		{name: "Unofficial", program: []byte{0x04, 0x11}, want: "*NOP $11 = 05"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := memory.NewMemory()
+			rom, _ := rom.NewROM(validrom)
+			cpu := NewCPU(newBusForTest(t, &memory, rom))
+			cpu.loadForTest(tt.program)
+			cpu.Reset(0x00_00)
+			cpu.registerX = 0x01
+			cpu.registerY = 0x01
+			cpu.Bus.WriteMemory(0x11, 0x05)
+			cpu.Bus.WriteMemoryUint16(0x12, 0x04_00)
+			cpu.Bus.WriteMemory(0x04_00, 0x07)
+			cpu.Bus.WriteMemory(0x04_01, 0x08)
+
+			var trace strings.Builder
+			cpu.Tracer = &trace
+			_, err := cpu.Step()
+			assert.NoError(t, err)
+
+			line := trace.String()
+			assert.Equal(t, tt.want, strings.TrimRight(line[15:48], " "))
+		})
+	}
+}
+
+func Test_ZeroPageWrap(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []byte
+	}{
+		{name: "ZeroPageX", program: []byte{0xB5, 0xF1}},
+		{name: "IndirectX", program: []byte{0xA1, 0xFE}},
+		{name: "IndirectY", program: []byte{0xB1, 0xFF}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := memory.NewMemory()
+			rom, _ := rom.NewROM(validrom)
+			cpu := NewCPU(newBusForTest(t, &memory, rom))
+			cpu.loadForTest(tt.program)
+			cpu.Reset(0x00_00)
+			cpu.registerX = 0x01
+			// 0xFF のポインタの上位バイトは 0x0100 ではなく 0x0000 (リセットベクタの下位 = 0x00) から読む
+			cpu.Bus.WriteMemory(0xFF, 0xF2)
+			cpu.Bus.WriteMemory(0x01_00, 0x04)
+			cpu.Bus.WriteMemory(0xF2, 0x42)
+			cpu.Bus.WriteMemory(0x04_F2, 0x99)
+			_, err := cpu.Step()
+
+			assert.NoError(t, err)
+			assert.Equal(t, byte(0x42), cpu.registerA)
+		})
+	}
+}
+
+// accessRecorder は 0x0400-0x05FF へのバスアクセスを記録する。
+type accessRecorder struct {
+	bus.Bus
+	accesses []string
+}
+
+func (r *accessRecorder) ReadMemory(address uint16) byte {
+	if 0x04_00 <= address && address < 0x06_00 {
+		r.accesses = append(r.accesses, fmt.Sprintf("R %04X", address))
+	}
+
+	return r.Bus.ReadMemory(address)
+}
+
+func (r *accessRecorder) WriteMemory(address uint16, data byte) {
+	if 0x04_00 <= address && address < 0x06_00 {
+		r.accesses = append(r.accesses, fmt.Sprintf("W %04X %02X", address, data))
+	}
+
+	r.Bus.WriteMemory(address, data)
+}
+
+func Test_BusAccesses(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []byte
+		want    []string
+	}{
+		{name: "Read/AbsoluteX", program: []byte{0xBD, 0x80, 0x04}, want: []string{"R 0481"}},
+		// ページをまたぐと、上位バイトを直す前のアドレスを先に読む
+		{name: "Read/AbsoluteX/PageCrossed", program: []byte{0xBD, 0xFF, 0x04}, want: []string{"R 0400", "R 0500"}},
+		{name: "Read/IndirectY/PageCrossed", program: []byte{0xB1, 0x10}, want: []string{"R 0400", "R 0500"}},
+		// 書き込みはページをまたがなくても先に読む
+		{name: "Write/AbsoluteX", program: []byte{0x9D, 0x80, 0x04}, want: []string{"R 0481", "W 0481 00"}},
+		{name: "Write/AbsoluteX/PageCrossed", program: []byte{0x9D, 0xFF, 0x04}, want: []string{"R 0400", "W 0500 00"}},
+		{name: "Write/IndirectY/PageCrossed", program: []byte{0x91, 0x10}, want: []string{"R 0400", "W 0500 00"}},
+		{name: "Write/Absolute", program: []byte{0x8D, 0x80, 0x04}, want: []string{"W 0480 00"}},
+		// RMW は読んだ値を書き戻してから結果を書き込む
+		{name: "ReadModifyWrite/Absolute", program: []byte{0xEE, 0x80, 0x04}, want: []string{"R 0480", "W 0480 05", "W 0480 06"}},
+		{name: "ReadModifyWrite/AbsoluteX", program: []byte{0x1E, 0x80, 0x04}, want: []string{"R 0481", "R 0481", "W 0481 05", "W 0481 0A"}},
+		{name: "ReadModifyWrite/Unofficial", program: []byte{0xDB, 0xFF, 0x04}, want: []string{"R 0400", "R 0500", "W 0500 07", "W 0500 06"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := memory.NewMemory()
+			rom, _ := rom.NewROM(validrom)
+			recorder := &accessRecorder{Bus: newBusForTest(t, &memory, rom)}
+			cpu := NewCPU(recorder)
+			cpu.loadForTest(tt.program)
+			cpu.Reset(0x00_00)
+			cpu.registerX = 0x01
+			cpu.registerY = 0x01
+			cpu.Bus.WriteMemoryUint16(0x10, 0x04_FF)
+			cpu.Bus.WriteMemory(0x04_80, 0x05)
+			cpu.Bus.WriteMemory(0x04_81, 0x05)
+			cpu.Bus.WriteMemory(0x05_00, 0x07)
+			recorder.accesses = nil
+
+			_, err := cpu.Step()
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, recorder.accesses)
+		})
+	}
+}
+
+func Test_DecimalMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant Variant
+		program []byte
+		a       byte
+		carry   bool
+		wantA   byte
+		wantC   bool
+	}{
+		{name: "ADC", variant: NMOS6502, program: []byte{0x69, 0x34}, a: 0x12, wantA: 0x46},
+		{name: "ADC/Carry", variant: NMOS6502, program: []byte{0x69, 0x46}, a: 0x58, carry: true, wantA: 0x05, wantC: true},
+		{name: "ADC/DigitCarry", variant: NMOS6502, program: []byte{0x69, 0x01}, a: 0x09, wantA: 0x10},
+		{name: "SBC", variant: NMOS6502, program: []byte{0xE9, 0x12}, a: 0x46, carry: true, wantA: 0x34, wantC: true},
+		{name: "SBC/Borrow", variant: NMOS6502, program: []byte{0xE9, 0x02}, a: 0x32, wantA: 0x29, wantC: true},
+		{name: "SBC/Negative", variant: NMOS6502, program: []byte{0xE9, 0x13}, a: 0x12, carry: true, wantA: 0x99},
+		// 2A03 は D フラグが立っていても 2 進数で計算する
+		{name: "ADC/2A03", variant: RP2A03, program: []byte{0x69, 0x01}, a: 0x09, wantA: 0x0A},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := memory.NewMemory()
+			rom, _ := rom.NewROM(validrom)
+			cpu := NewCPU(newBusForTest(t, &memory, rom))
+			cpu.Variant = tt.variant
+			cpu.loadForTest(tt.program)
+			cpu.Reset(0x00_00)
+			cpu.registerA = tt.a
+			cpu.status.setC(tt.carry)
+			cpu.status.setD(true)
+			_, err := cpu.Step()
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantA, cpu.registerA)
+			assert.Equal(t, tt.wantC, cpu.status.c())
+		})
+	}
+}
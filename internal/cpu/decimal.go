@@ -0,0 +1,88 @@
+package cpu
+
+// Variant は CPU の種類。
+type Variant int
+
+const (
+	// RP2A03 はファミコンの CPU。10 進モードの回路がなく、D フラグは ADC と SBC に影響しない。
+	RP2A03 Variant = iota
+	// NMOS6502 は汎用の NMOS 6502。D フラグが立っていると ADC と SBC は BCD で計算する。
+	NMOS6502
+)
+
+// add は ADC の計算をする。
+func (cpu *CPU) add(value byte) {
+	if cpu.Variant == NMOS6502 && cpu.status.d() {
+		cpu.addDecimal(value)
+
+		return
+	}
+
+	cpu.addWithCarry(value)
+}
+
+// subtract は SBC の計算をする。
+func (cpu *CPU) subtract(value byte) {
+	if cpu.Variant == NMOS6502 && cpu.status.d() {
+		cpu.subtractDecimal(value)
+
+		return
+	}
+
+	// A - M - (1 - C) は A + ^M + C と等しい
+	cpu.addWithCarry(^value)
+}
+
+// addDecimal は BCD で A にキャリー付きで value を加える。
+// NMOS 6502 では Z は 2 進数で足した結果で決まり、N と V は下位桁を補正したあと上位桁を補正する前の値で決まる。
+// BCD でない値を渡したときの結果も実機と同じになる。
+//
+// http://www.6502.org/tutorials/decimal_mode.html#A
+func (cpu *CPU) addDecimal(value byte) {
+	a := cpu.registerA
+	var carry int
+	if cpu.status.c() {
+		carry = 1
+	}
+
+	low := int(a&0x0F) + int(value&0x0F) + carry
+	if low >= 0x0A {
+		low = ((low + 0x06) & 0x0F) + 0x10
+	}
+	result := int(a&0xF0) + int(value&0xF0) + low
+
+	signed := int(int8(a&0xF0)) + int(int8(value&0xF0)) + low
+	cpu.status.setN(signed&0b1000_0000 != 0)
+	cpu.status.setO(signed < -128 || signed > 127)
+	cpu.status.setZ(byte(int(a)+int(value)+carry) == 0)
+
+	if result >= 0xA0 {
+		result += 0x60
+	}
+	cpu.status.setC(result >= 0x1_00)
+
+	cpu.registerA = byte(result)
+}
+
+// subtractDecimal は BCD で A から value とボローを引く。NMOS 6502 ではフラグは 2 進数で引いたときと同じになる。
+//
+// http://www.6502.org/tutorials/decimal_mode.html#A
+func (cpu *CPU) subtractDecimal(value byte) {
+	a := cpu.registerA
+	var borrow int
+	if !cpu.status.c() {
+		borrow = 1
+	}
+
+	low := int(a&0x0F) - int(value&0x0F) - borrow
+	if low < 0 {
+		low = ((low - 0x06) & 0x0F) - 0x10
+	}
+	result := int(a&0xF0) - int(value&0xF0) + low
+	if result < 0 {
+		result -= 0x60
+	}
+
+	cpu.addWithCarry(^value)
+	cpu.registerA = byte(result)
+}
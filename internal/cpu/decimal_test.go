@@ -0,0 +1,51 @@
+// asm パッケージが cpu に依存しているため、外部テストパッケージにする。
+package cpu_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tabo-syu/famicom/internal/asm"
+	"github.com/tabo-syu/famicom/internal/bus"
+	"github.com/tabo-syu/famicom/internal/cpu"
+	"github.com/tabo-syu/famicom/internal/memory"
+	"github.com/tabo-syu/famicom/internal/rom"
+)
+
+// runDecimalTest は Bruce Clark の 10 進モードのテストを variant の CPU で実行し、ERROR の値を返す。
+func runDecimalTest(t *testing.T, variant cpu.Variant) byte {
+	source, err := os.ReadFile("testdata/decimal.asm")
+	assert.NoError(t, err)
+	program, err := asm.Assemble(string(source))
+	assert.NoError(t, err)
+
+	memory := memory.NewMemory()
+	rom, _ := rom.NewROM([]byte{'N', 'E', 'S', 0x1A, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	b, err := bus.NewBus(&memory, rom)
+	assert.NoError(t, err)
+	c := cpu.NewCPU(b)
+	c.Variant = variant
+	c.HaltOnBRK = true
+	c.ClockRate = 0
+	c.Load(program.Code)
+	c.Bus.WriteMemoryUint16(0x07_FE, program.Origin)
+	c.Reset(0x07_FE)
+
+	assert.ErrorIs(t, c.Run(context.Background()), cpu.ErrBRK)
+
+	return c.Bus.ReadMemory(0x00)
+}
+
+func Test_Decimal_BruceClark(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs every operand and carry combination")
+	}
+
+	assert.Equal(t, byte(0), runDecimalTest(t, cpu.NMOS6502))
+}
+
+func Test_Decimal_BruceClark_2A03IgnoresDecimalFlag(t *testing.T) {
+	assert.Equal(t, byte(1), runDecimalTest(t, cpu.RP2A03))
+}
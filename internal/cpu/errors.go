@@ -0,0 +1,42 @@
+package cpu
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBRK は HaltOnBRK が有効なときに BRK を実行したことを表す。
+var ErrBRK = errors.New("BRK called")
+
+// UnknownOpcodeError は命令表にないオペコードを実行しようとしたことを表す。
+type UnknownOpcodeError struct {
+	Opcode  byte
+	Address uint16
+}
+
+func (e *UnknownOpcodeError) Error() string {
+	return fmt.Sprintf("unknown opcode 0x%02X at 0x%04X", e.Opcode, e.Address)
+}
+
+// JamError は CPU を停止させるオペコード (JAM/KIL) を実行したことを表す。
+// 停止した CPU はリセットされるまで同じ番地に留まる。
+//
+// https://www.nesdev.org/wiki/CPU_unofficial_opcodes
+type JamError struct {
+	Opcode  byte
+	Address uint16
+}
+
+func (e *JamError) Error() string {
+	return fmt.Sprintf("CPU jammed by opcode 0x%02X at 0x%04X", e.Opcode, e.Address)
+}
+
+// jams は CPU を停止させるオペコードかどうかを返す。
+func jams(code byte) bool {
+	switch code {
+	case 0x02, 0x12, 0x22, 0x32, 0x42, 0x52, 0x62, 0x72, 0x92, 0xB2, 0xD2, 0xF2:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,359 +1,245 @@
 // Code generated by cmd/opcode_scraper/main.go; DO NOT EDIT.
 package cpu
 
-import "errors"
-
-type opcode func(mode addressingMode)
+// operation は命令の処理。
+type operation func(cpu *CPU, mode AddressingMode) error
 
 type instruction struct {
-	opcode string
-	bytes  uint16
-	cycles uint16
-	mode   addressingMode
+	opcode         string
+	operation      operation
+	bytes          uint16
+	cycles         uint16
+	mode           AddressingMode
+	pageCrossCycle bool
+	unofficial     bool
 }
 
-func (i instruction) Call(cpu *CPU) error {
-	var err error
-
-	switch i.opcode {
-	case "ADC":
-		err = cpu.ADC(i.mode)
-	case "AND":
-		err = cpu.AND(i.mode)
-	case "ASL":
-		err = cpu.ASL(i.mode)
-	case "BCC":
-		err = cpu.BCC(i.mode)
-	case "BCS":
-		err = cpu.BCS(i.mode)
-	case "BEQ":
-		err = cpu.BEQ(i.mode)
-	case "BIT":
-		err = cpu.BIT(i.mode)
-	case "BMI":
-		err = cpu.BMI(i.mode)
-	case "BNE":
-		err = cpu.BNE(i.mode)
-	case "BPL":
-		err = cpu.BPL(i.mode)
-	case "BRK":
-		err = cpu.BRK(i.mode)
-	case "BVC":
-		err = cpu.BVC(i.mode)
-	case "BVS":
-		err = cpu.BVS(i.mode)
-	case "CLC":
-		err = cpu.CLC(i.mode)
-	case "CLD":
-		err = cpu.CLD(i.mode)
-	case "CLI":
-		err = cpu.CLI(i.mode)
-	case "CLV":
-		err = cpu.CLV(i.mode)
-	case "CMP":
-		err = cpu.CMP(i.mode)
-	case "CPX":
-		err = cpu.CPX(i.mode)
-	case "CPY":
-		err = cpu.CPY(i.mode)
-	case "DEC":
-		err = cpu.DEC(i.mode)
-	case "DEX":
-		err = cpu.DEX(i.mode)
-	case "DEY":
-		err = cpu.DEY(i.mode)
-	case "EOR":
-		err = cpu.EOR(i.mode)
-	case "INC":
-		err = cpu.INC(i.mode)
-	case "INX":
-		err = cpu.INX(i.mode)
-	case "INY":
-		err = cpu.INY(i.mode)
-	case "JMP":
-		err = cpu.JMP(i.mode)
-	case "JSR":
-		err = cpu.JSR(i.mode)
-	case "LDA":
-		err = cpu.LDA(i.mode)
-	case "LDX":
-		err = cpu.LDX(i.mode)
-	case "LDY":
-		err = cpu.LDY(i.mode)
-	case "LSR":
-		err = cpu.LSR(i.mode)
-	case "NOP":
-		err = cpu.NOP(i.mode)
-	case "ORA":
-		err = cpu.ORA(i.mode)
-	case "PHA":
-		err = cpu.PHA(i.mode)
-	case "PHP":
-		err = cpu.PHP(i.mode)
-	case "PLA":
-		err = cpu.PLA(i.mode)
-	case "PLP":
-		err = cpu.PLP(i.mode)
-	case "ROL":
-		err = cpu.ROL(i.mode)
-	case "ROR":
-		err = cpu.ROR(i.mode)
-	case "RTI":
-		err = cpu.RTI(i.mode)
-	case "RTS":
-		err = cpu.RTS(i.mode)
-	case "SBC":
-		err = cpu.SBC(i.mode)
-	case "SEC":
-		err = cpu.SEC(i.mode)
-	case "SED":
-		err = cpu.SED(i.mode)
-	case "SEI":
-		err = cpu.SEI(i.mode)
-	case "STA":
-		err = cpu.STA(i.mode)
-	case "STX":
-		err = cpu.STX(i.mode)
-	case "STY":
-		err = cpu.STY(i.mode)
-	case "TAX":
-		err = cpu.TAX(i.mode)
-	case "TAY":
-		err = cpu.TAY(i.mode)
-	case "TSX":
-		err = cpu.TSX(i.mode)
-	case "TXA":
-		err = cpu.TXA(i.mode)
-	case "TXS":
-		err = cpu.TXS(i.mode)
-	case "TYA":
-		err = cpu.TYA(i.mode)
-	default:
-		return errors.New("unexpected opcode")
-	}
-
+func (i *instruction) Call(cpu *CPU) error {
+	err := i.operation(cpu, i.mode)
 	cpu.ProgramCounter += i.bytes - 1
 
 	return err
 }
 
-func newInstruction(opcode string, bytes uint16, cycles uint16, mode addressingMode) instruction {
+func newInstruction(opcode string, operation operation, bytes uint16, cycles uint16, mode AddressingMode, pageCrossCycle bool) instruction {
 	return instruction{
-		opcode: opcode,
-		bytes:  bytes,
-		cycles: cycles,
-		mode:   mode,
+		opcode:         opcode,
+		operation:      operation,
+		bytes:          bytes,
+		cycles:         cycles,
+		mode:           mode,
+		pageCrossCycle: pageCrossCycle,
 	}
 }
 
-func NewInstructions() map[byte]instruction {
-	return map[byte]instruction{
+func NewInstructions() [256]instruction {
+	return [256]instruction{
 		// ADC
-		0x69: newInstruction("ADC", 2, 2, ImmediateMode),
-		0x65: newInstruction("ADC", 2, 3, ZeroPageMode),
-		0x75: newInstruction("ADC", 2, 4, ZeroPageXMode),
-		0x6D: newInstruction("ADC", 3, 4, AbsoluteMode),
-		0x7D: newInstruction("ADC", 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode),
-		0x79: newInstruction("ADC", 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode),
-		0x61: newInstruction("ADC", 2, 6, IndirectXMode),
-		0x71: newInstruction("ADC", 2, 5 /*(+1 if page crossed)*/, IndirectYMode),
+		0x69: newInstruction("ADC", (*CPU).ADC, 2, 2, ImmediateMode, false),
+		0x65: newInstruction("ADC", (*CPU).ADC, 2, 3, ZeroPageMode, false),
+		0x75: newInstruction("ADC", (*CPU).ADC, 2, 4, ZeroPageXMode, false),
+		0x6D: newInstruction("ADC", (*CPU).ADC, 3, 4, AbsoluteMode, false),
+		0x7D: newInstruction("ADC", (*CPU).ADC, 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode, true),
+		0x79: newInstruction("ADC", (*CPU).ADC, 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode, true),
+		0x61: newInstruction("ADC", (*CPU).ADC, 2, 6, IndirectXMode, false),
+		0x71: newInstruction("ADC", (*CPU).ADC, 2, 5 /*(+1 if page crossed)*/, IndirectYMode, true),
 		// AND
-		0x29: newInstruction("AND", 2, 2, ImmediateMode),
-		0x25: newInstruction("AND", 2, 3, ZeroPageMode),
-		0x35: newInstruction("AND", 2, 4, ZeroPageXMode),
-		0x2D: newInstruction("AND", 3, 4, AbsoluteMode),
-		0x3D: newInstruction("AND", 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode),
-		0x39: newInstruction("AND", 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode),
-		0x21: newInstruction("AND", 2, 6, IndirectXMode),
-		0x31: newInstruction("AND", 2, 5 /*(+1 if page crossed)*/, IndirectYMode),
+		0x29: newInstruction("AND", (*CPU).AND, 2, 2, ImmediateMode, false),
+		0x25: newInstruction("AND", (*CPU).AND, 2, 3, ZeroPageMode, false),
+		0x35: newInstruction("AND", (*CPU).AND, 2, 4, ZeroPageXMode, false),
+		0x2D: newInstruction("AND", (*CPU).AND, 3, 4, AbsoluteMode, false),
+		0x3D: newInstruction("AND", (*CPU).AND, 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode, true),
+		0x39: newInstruction("AND", (*CPU).AND, 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode, true),
+		0x21: newInstruction("AND", (*CPU).AND, 2, 6, IndirectXMode, false),
+		0x31: newInstruction("AND", (*CPU).AND, 2, 5 /*(+1 if page crossed)*/, IndirectYMode, true),
 		// ASL
-		0x0A: newInstruction("ASL", 1, 2, AccumulatorMode),
-		0x06: newInstruction("ASL", 2, 5, ZeroPageMode),
-		0x16: newInstruction("ASL", 2, 6, ZeroPageXMode),
-		0x0E: newInstruction("ASL", 3, 6, AbsoluteMode),
-		0x1E: newInstruction("ASL", 3, 7, AbsoluteXMode),
+		0x0A: newInstruction("ASL", (*CPU).ASL, 1, 2, AccumulatorMode, false),
+		0x06: newInstruction("ASL", (*CPU).ASL, 2, 5, ZeroPageMode, false),
+		0x16: newInstruction("ASL", (*CPU).ASL, 2, 6, ZeroPageXMode, false),
+		0x0E: newInstruction("ASL", (*CPU).ASL, 3, 6, AbsoluteMode, false),
+		0x1E: newInstruction("ASL", (*CPU).ASL, 3, 7, AbsoluteXMode, false),
 		// BCC
-		0x90: newInstruction("BCC", 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode),
+		0x90: newInstruction("BCC", (*CPU).BCC, 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode, false),
 		// BCS
-		0xB0: newInstruction("BCS", 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode),
+		0xB0: newInstruction("BCS", (*CPU).BCS, 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode, false),
 		// BEQ
-		0xF0: newInstruction("BEQ", 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode),
+		0xF0: newInstruction("BEQ", (*CPU).BEQ, 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode, false),
 		// BIT
-		0x24: newInstruction("BIT", 2, 3, ZeroPageMode),
-		0x2C: newInstruction("BIT", 3, 4, AbsoluteMode),
+		0x24: newInstruction("BIT", (*CPU).BIT, 2, 3, ZeroPageMode, false),
+		0x2C: newInstruction("BIT", (*CPU).BIT, 3, 4, AbsoluteMode, false),
 		// BMI
-		0x30: newInstruction("BMI", 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode),
+		0x30: newInstruction("BMI", (*CPU).BMI, 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode, false),
 		// BNE
-		0xD0: newInstruction("BNE", 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode),
+		0xD0: newInstruction("BNE", (*CPU).BNE, 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode, false),
 		// BPL
-		0x10: newInstruction("BPL", 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode),
+		0x10: newInstruction("BPL", (*CPU).BPL, 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode, false),
 		// BRK
-		0x00: newInstruction("BRK", 1, 7, ImpliedMode),
+		0x00: newInstruction("BRK", (*CPU).BRK, 1, 7, ImpliedMode, false),
 		// BVC
-		0x50: newInstruction("BVC", 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode),
+		0x50: newInstruction("BVC", (*CPU).BVC, 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode, false),
 		// BVS
-		0x70: newInstruction("BVS", 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode),
+		0x70: newInstruction("BVS", (*CPU).BVS, 2, 2 /*(+1 if branch succeeds, +2 if to a new page)*/, RelativeMode, false),
 		// CLC
-		0x18: newInstruction("CLC", 1, 2, ImpliedMode),
+		0x18: newInstruction("CLC", (*CPU).CLC, 1, 2, ImpliedMode, false),
 		// CLD
-		0xD8: newInstruction("CLD", 1, 2, ImpliedMode),
+		0xD8: newInstruction("CLD", (*CPU).CLD, 1, 2, ImpliedMode, false),
 		// CLI
-		0x58: newInstruction("CLI", 1, 2, ImpliedMode),
+		0x58: newInstruction("CLI", (*CPU).CLI, 1, 2, ImpliedMode, false),
 		// CLV
-		0xB8: newInstruction("CLV", 1, 2, ImpliedMode),
+		0xB8: newInstruction("CLV", (*CPU).CLV, 1, 2, ImpliedMode, false),
 		// CMP
-		0xC9: newInstruction("CMP", 2, 2, ImmediateMode),
-		0xC5: newInstruction("CMP", 2, 3, ZeroPageMode),
-		0xD5: newInstruction("CMP", 2, 4, ZeroPageXMode),
-		0xCD: newInstruction("CMP", 3, 4, AbsoluteMode),
-		0xDD: newInstruction("CMP", 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode),
-		0xD9: newInstruction("CMP", 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode),
-		0xC1: newInstruction("CMP", 2, 6, IndirectXMode),
-		0xD1: newInstruction("CMP", 2, 5 /*(+1 if page crossed)*/, IndirectYMode),
+		0xC9: newInstruction("CMP", (*CPU).CMP, 2, 2, ImmediateMode, false),
+		0xC5: newInstruction("CMP", (*CPU).CMP, 2, 3, ZeroPageMode, false),
+		0xD5: newInstruction("CMP", (*CPU).CMP, 2, 4, ZeroPageXMode, false),
+		0xCD: newInstruction("CMP", (*CPU).CMP, 3, 4, AbsoluteMode, false),
+		0xDD: newInstruction("CMP", (*CPU).CMP, 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode, true),
+		0xD9: newInstruction("CMP", (*CPU).CMP, 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode, true),
+		0xC1: newInstruction("CMP", (*CPU).CMP, 2, 6, IndirectXMode, false),
+		0xD1: newInstruction("CMP", (*CPU).CMP, 2, 5 /*(+1 if page crossed)*/, IndirectYMode, true),
 		// CPX
-		0xE0: newInstruction("CPX", 2, 2, ImmediateMode),
-		0xE4: newInstruction("CPX", 2, 3, ZeroPageMode),
-		0xEC: newInstruction("CPX", 3, 4, AbsoluteMode),
+		0xE0: newInstruction("CPX", (*CPU).CPX, 2, 2, ImmediateMode, false),
+		0xE4: newInstruction("CPX", (*CPU).CPX, 2, 3, ZeroPageMode, false),
+		0xEC: newInstruction("CPX", (*CPU).CPX, 3, 4, AbsoluteMode, false),
 		// CPY
-		0xC0: newInstruction("CPY", 2, 2, ImmediateMode),
-		0xC4: newInstruction("CPY", 2, 3, ZeroPageMode),
-		0xCC: newInstruction("CPY", 3, 4, AbsoluteMode),
+		0xC0: newInstruction("CPY", (*CPU).CPY, 2, 2, ImmediateMode, false),
+		0xC4: newInstruction("CPY", (*CPU).CPY, 2, 3, ZeroPageMode, false),
+		0xCC: newInstruction("CPY", (*CPU).CPY, 3, 4, AbsoluteMode, false),
 		// DEC
-		0xC6: newInstruction("DEC", 2, 5, ZeroPageMode),
-		0xD6: newInstruction("DEC", 2, 6, ZeroPageXMode),
-		0xCE: newInstruction("DEC", 3, 6, AbsoluteMode),
-		0xDE: newInstruction("DEC", 3, 7, AbsoluteXMode),
+		0xC6: newInstruction("DEC", (*CPU).DEC, 2, 5, ZeroPageMode, false),
+		0xD6: newInstruction("DEC", (*CPU).DEC, 2, 6, ZeroPageXMode, false),
+		0xCE: newInstruction("DEC", (*CPU).DEC, 3, 6, AbsoluteMode, false),
+		0xDE: newInstruction("DEC", (*CPU).DEC, 3, 7, AbsoluteXMode, false),
 		// DEX
-		0xCA: newInstruction("DEX", 1, 2, ImpliedMode),
+		0xCA: newInstruction("DEX", (*CPU).DEX, 1, 2, ImpliedMode, false),
 		// DEY
-		0x88: newInstruction("DEY", 1, 2, ImpliedMode),
+		0x88: newInstruction("DEY", (*CPU).DEY, 1, 2, ImpliedMode, false),
 		// EOR
-		0x49: newInstruction("EOR", 2, 2, ImmediateMode),
-		0x45: newInstruction("EOR", 2, 3, ZeroPageMode),
-		0x55: newInstruction("EOR", 2, 4, ZeroPageXMode),
-		0x4D: newInstruction("EOR", 3, 4, AbsoluteMode),
-		0x5D: newInstruction("EOR", 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode),
-		0x59: newInstruction("EOR", 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode),
-		0x41: newInstruction("EOR", 2, 6, IndirectXMode),
-		0x51: newInstruction("EOR", 2, 5 /*(+1 if page crossed)*/, IndirectYMode),
+		0x49: newInstruction("EOR", (*CPU).EOR, 2, 2, ImmediateMode, false),
+		0x45: newInstruction("EOR", (*CPU).EOR, 2, 3, ZeroPageMode, false),
+		0x55: newInstruction("EOR", (*CPU).EOR, 2, 4, ZeroPageXMode, false),
+		0x4D: newInstruction("EOR", (*CPU).EOR, 3, 4, AbsoluteMode, false),
+		0x5D: newInstruction("EOR", (*CPU).EOR, 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode, true),
+		0x59: newInstruction("EOR", (*CPU).EOR, 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode, true),
+		0x41: newInstruction("EOR", (*CPU).EOR, 2, 6, IndirectXMode, false),
+		0x51: newInstruction("EOR", (*CPU).EOR, 2, 5 /*(+1 if page crossed)*/, IndirectYMode, true),
 		// INC
-		0xE6: newInstruction("INC", 2, 5, ZeroPageMode),
-		0xF6: newInstruction("INC", 2, 6, ZeroPageXMode),
-		0xEE: newInstruction("INC", 3, 6, AbsoluteMode),
-		0xFE: newInstruction("INC", 3, 7, AbsoluteXMode),
+		0xE6: newInstruction("INC", (*CPU).INC, 2, 5, ZeroPageMode, false),
+		0xF6: newInstruction("INC", (*CPU).INC, 2, 6, ZeroPageXMode, false),
+		0xEE: newInstruction("INC", (*CPU).INC, 3, 6, AbsoluteMode, false),
+		0xFE: newInstruction("INC", (*CPU).INC, 3, 7, AbsoluteXMode, false),
 		// INX
-		0xE8: newInstruction("INX", 1, 2, ImpliedMode),
+		0xE8: newInstruction("INX", (*CPU).INX, 1, 2, ImpliedMode, false),
 		// INY
-		0xC8: newInstruction("INY", 1, 2, ImpliedMode),
+		0xC8: newInstruction("INY", (*CPU).INY, 1, 2, ImpliedMode, false),
 		// JMP
-		0x4C: newInstruction("JMP", 3, 3, AbsoluteMode),
-		0x6C: newInstruction("JMP", 3, 5, IndirectMode),
+		0x4C: newInstruction("JMP", (*CPU).JMP, 3, 3, AbsoluteMode, false),
+		0x6C: newInstruction("JMP", (*CPU).JMP, 3, 5, IndirectMode, false),
 		// JSR
-		0x20: newInstruction("JSR", 3, 6, AbsoluteMode),
+		0x20: newInstruction("JSR", (*CPU).JSR, 3, 6, AbsoluteMode, false),
 		// LDA
-		0xA9: newInstruction("LDA", 2, 2, ImmediateMode),
-		0xA5: newInstruction("LDA", 2, 3, ZeroPageMode),
-		0xB5: newInstruction("LDA", 2, 4, ZeroPageXMode),
-		0xAD: newInstruction("LDA", 3, 4, AbsoluteMode),
-		0xBD: newInstruction("LDA", 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode),
-		0xB9: newInstruction("LDA", 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode),
-		0xA1: newInstruction("LDA", 2, 6, IndirectXMode),
-		0xB1: newInstruction("LDA", 2, 5 /*(+1 if page crossed)*/, IndirectYMode),
+		0xA9: newInstruction("LDA", (*CPU).LDA, 2, 2, ImmediateMode, false),
+		0xA5: newInstruction("LDA", (*CPU).LDA, 2, 3, ZeroPageMode, false),
+		0xB5: newInstruction("LDA", (*CPU).LDA, 2, 4, ZeroPageXMode, false),
+		0xAD: newInstruction("LDA", (*CPU).LDA, 3, 4, AbsoluteMode, false),
+		0xBD: newInstruction("LDA", (*CPU).LDA, 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode, true),
+		0xB9: newInstruction("LDA", (*CPU).LDA, 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode, true),
+		0xA1: newInstruction("LDA", (*CPU).LDA, 2, 6, IndirectXMode, false),
+		0xB1: newInstruction("LDA", (*CPU).LDA, 2, 5 /*(+1 if page crossed)*/, IndirectYMode, true),
 		// LDX
-		0xA2: newInstruction("LDX", 2, 2, ImmediateMode),
-		0xA6: newInstruction("LDX", 2, 3, ZeroPageMode),
-		0xB6: newInstruction("LDX", 2, 4, ZeroPageYMode),
-		0xAE: newInstruction("LDX", 3, 4, AbsoluteMode),
-		0xBE: newInstruction("LDX", 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode),
+		0xA2: newInstruction("LDX", (*CPU).LDX, 2, 2, ImmediateMode, false),
+		0xA6: newInstruction("LDX", (*CPU).LDX, 2, 3, ZeroPageMode, false),
+		0xB6: newInstruction("LDX", (*CPU).LDX, 2, 4, ZeroPageYMode, false),
+		0xAE: newInstruction("LDX", (*CPU).LDX, 3, 4, AbsoluteMode, false),
+		0xBE: newInstruction("LDX", (*CPU).LDX, 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode, true),
 		// LDY
-		0xA0: newInstruction("LDY", 2, 2, ImmediateMode),
-		0xA4: newInstruction("LDY", 2, 3, ZeroPageMode),
-		0xB4: newInstruction("LDY", 2, 4, ZeroPageXMode),
-		0xAC: newInstruction("LDY", 3, 4, AbsoluteMode),
-		0xBC: newInstruction("LDY", 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode),
+		0xA0: newInstruction("LDY", (*CPU).LDY, 2, 2, ImmediateMode, false),
+		0xA4: newInstruction("LDY", (*CPU).LDY, 2, 3, ZeroPageMode, false),
+		0xB4: newInstruction("LDY", (*CPU).LDY, 2, 4, ZeroPageXMode, false),
+		0xAC: newInstruction("LDY", (*CPU).LDY, 3, 4, AbsoluteMode, false),
+		0xBC: newInstruction("LDY", (*CPU).LDY, 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode, true),
 		// LSR
-		0x4A: newInstruction("LSR", 1, 2, AccumulatorMode),
-		0x46: newInstruction("LSR", 2, 5, ZeroPageMode),
-		0x56: newInstruction("LSR", 2, 6, ZeroPageXMode),
-		0x4E: newInstruction("LSR", 3, 6, AbsoluteMode),
-		0x5E: newInstruction("LSR", 3, 7, AbsoluteXMode),
+		0x4A: newInstruction("LSR", (*CPU).LSR, 1, 2, AccumulatorMode, false),
+		0x46: newInstruction("LSR", (*CPU).LSR, 2, 5, ZeroPageMode, false),
+		0x56: newInstruction("LSR", (*CPU).LSR, 2, 6, ZeroPageXMode, false),
+		0x4E: newInstruction("LSR", (*CPU).LSR, 3, 6, AbsoluteMode, false),
+		0x5E: newInstruction("LSR", (*CPU).LSR, 3, 7, AbsoluteXMode, false),
 		// NOP
-		0xEA: newInstruction("NOP", 1, 2, ImpliedMode),
+		0xEA: newInstruction("NOP", (*CPU).NOP, 1, 2, ImpliedMode, false),
 		// ORA
-		0x09: newInstruction("ORA", 2, 2, ImmediateMode),
-		0x05: newInstruction("ORA", 2, 3, ZeroPageMode),
-		0x15: newInstruction("ORA", 2, 4, ZeroPageXMode),
-		0x0D: newInstruction("ORA", 3, 4, AbsoluteMode),
-		0x1D: newInstruction("ORA", 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode),
-		0x19: newInstruction("ORA", 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode),
-		0x01: newInstruction("ORA", 2, 6, IndirectXMode),
-		0x11: newInstruction("ORA", 2, 5 /*(+1 if page crossed)*/, IndirectYMode),
+		0x09: newInstruction("ORA", (*CPU).ORA, 2, 2, ImmediateMode, false),
+		0x05: newInstruction("ORA", (*CPU).ORA, 2, 3, ZeroPageMode, false),
+		0x15: newInstruction("ORA", (*CPU).ORA, 2, 4, ZeroPageXMode, false),
+		0x0D: newInstruction("ORA", (*CPU).ORA, 3, 4, AbsoluteMode, false),
+		0x1D: newInstruction("ORA", (*CPU).ORA, 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode, true),
+		0x19: newInstruction("ORA", (*CPU).ORA, 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode, true),
+		0x01: newInstruction("ORA", (*CPU).ORA, 2, 6, IndirectXMode, false),
+		0x11: newInstruction("ORA", (*CPU).ORA, 2, 5 /*(+1 if page crossed)*/, IndirectYMode, true),
 		// PHA
-		0x48: newInstruction("PHA", 1, 3, ImpliedMode),
+		0x48: newInstruction("PHA", (*CPU).PHA, 1, 3, ImpliedMode, false),
 		// PHP
-		0x08: newInstruction("PHP", 1, 3, ImpliedMode),
+		0x08: newInstruction("PHP", (*CPU).PHP, 1, 3, ImpliedMode, false),
 		// PLA
-		0x68: newInstruction("PLA", 1, 4, ImpliedMode),
+		0x68: newInstruction("PLA", (*CPU).PLA, 1, 4, ImpliedMode, false),
 		// PLP
-		0x28: newInstruction("PLP", 1, 4, ImpliedMode),
+		0x28: newInstruction("PLP", (*CPU).PLP, 1, 4, ImpliedMode, false),
 		// ROL
-		0x2A: newInstruction("ROL", 1, 2, AccumulatorMode),
-		0x26: newInstruction("ROL", 2, 5, ZeroPageMode),
-		0x36: newInstruction("ROL", 2, 6, ZeroPageXMode),
-		0x2E: newInstruction("ROL", 3, 6, AbsoluteMode),
-		0x3E: newInstruction("ROL", 3, 7, AbsoluteXMode),
+		0x2A: newInstruction("ROL", (*CPU).ROL, 1, 2, AccumulatorMode, false),
+		0x26: newInstruction("ROL", (*CPU).ROL, 2, 5, ZeroPageMode, false),
+		0x36: newInstruction("ROL", (*CPU).ROL, 2, 6, ZeroPageXMode, false),
+		0x2E: newInstruction("ROL", (*CPU).ROL, 3, 6, AbsoluteMode, false),
+		0x3E: newInstruction("ROL", (*CPU).ROL, 3, 7, AbsoluteXMode, false),
 		// ROR
-		0x6A: newInstruction("ROR", 1, 2, AccumulatorMode),
-		0x66: newInstruction("ROR", 2, 5, ZeroPageMode),
-		0x76: newInstruction("ROR", 2, 6, ZeroPageXMode),
-		0x6E: newInstruction("ROR", 3, 6, AbsoluteMode),
-		0x7E: newInstruction("ROR", 3, 7, AbsoluteXMode),
+		0x6A: newInstruction("ROR", (*CPU).ROR, 1, 2, AccumulatorMode, false),
+		0x66: newInstruction("ROR", (*CPU).ROR, 2, 5, ZeroPageMode, false),
+		0x76: newInstruction("ROR", (*CPU).ROR, 2, 6, ZeroPageXMode, false),
+		0x6E: newInstruction("ROR", (*CPU).ROR, 3, 6, AbsoluteMode, false),
+		0x7E: newInstruction("ROR", (*CPU).ROR, 3, 7, AbsoluteXMode, false),
 		// RTI
-		0x40: newInstruction("RTI", 1, 6, ImpliedMode),
+		0x40: newInstruction("RTI", (*CPU).RTI, 1, 6, ImpliedMode, false),
 		// RTS
-		0x60: newInstruction("RTS", 1, 6, ImpliedMode),
+		0x60: newInstruction("RTS", (*CPU).RTS, 1, 6, ImpliedMode, false),
 		// SBC
-		0xE9: newInstruction("SBC", 2, 2, ImmediateMode),
-		0xE5: newInstruction("SBC", 2, 3, ZeroPageMode),
-		0xF5: newInstruction("SBC", 2, 4, ZeroPageXMode),
-		0xED: newInstruction("SBC", 3, 4, AbsoluteMode),
-		0xFD: newInstruction("SBC", 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode),
-		0xF9: newInstruction("SBC", 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode),
-		0xE1: newInstruction("SBC", 2, 6, IndirectXMode),
-		0xF1: newInstruction("SBC", 2, 5 /*(+1 if page crossed)*/, IndirectYMode),
+		0xE9: newInstruction("SBC", (*CPU).SBC, 2, 2, ImmediateMode, false),
+		0xE5: newInstruction("SBC", (*CPU).SBC, 2, 3, ZeroPageMode, false),
+		0xF5: newInstruction("SBC", (*CPU).SBC, 2, 4, ZeroPageXMode, false),
+		0xED: newInstruction("SBC", (*CPU).SBC, 3, 4, AbsoluteMode, false),
+		0xFD: newInstruction("SBC", (*CPU).SBC, 3, 4 /*(+1 if page crossed)*/, AbsoluteXMode, true),
+		0xF9: newInstruction("SBC", (*CPU).SBC, 3, 4 /*(+1 if page crossed)*/, AbsoluteYMode, true),
+		0xE1: newInstruction("SBC", (*CPU).SBC, 2, 6, IndirectXMode, false),
+		0xF1: newInstruction("SBC", (*CPU).SBC, 2, 5 /*(+1 if page crossed)*/, IndirectYMode, true),
 		// SEC
-		0x38: newInstruction("SEC", 1, 2, ImpliedMode),
+		0x38: newInstruction("SEC", (*CPU).SEC, 1, 2, ImpliedMode, false),
 		// SED
-		0xF8: newInstruction("SED", 1, 2, ImpliedMode),
+		0xF8: newInstruction("SED", (*CPU).SED, 1, 2, ImpliedMode, false),
 		// SEI
-		0x78: newInstruction("SEI", 1, 2, ImpliedMode),
+		0x78: newInstruction("SEI", (*CPU).SEI, 1, 2, ImpliedMode, false),
 		// STA
-		0x85: newInstruction("STA", 2, 3, ZeroPageMode),
-		0x95: newInstruction("STA", 2, 4, ZeroPageXMode),
-		0x8D: newInstruction("STA", 3, 4, AbsoluteMode),
-		0x9D: newInstruction("STA", 3, 5, AbsoluteXMode),
-		0x99: newInstruction("STA", 3, 5, AbsoluteYMode),
-		0x81: newInstruction("STA", 2, 6, IndirectXMode),
-		0x91: newInstruction("STA", 2, 6, IndirectYMode),
+		0x85: newInstruction("STA", (*CPU).STA, 2, 3, ZeroPageMode, false),
+		0x95: newInstruction("STA", (*CPU).STA, 2, 4, ZeroPageXMode, false),
+		0x8D: newInstruction("STA", (*CPU).STA, 3, 4, AbsoluteMode, false),
+		0x9D: newInstruction("STA", (*CPU).STA, 3, 5, AbsoluteXMode, false),
+		0x99: newInstruction("STA", (*CPU).STA, 3, 5, AbsoluteYMode, false),
+		0x81: newInstruction("STA", (*CPU).STA, 2, 6, IndirectXMode, false),
+		0x91: newInstruction("STA", (*CPU).STA, 2, 6, IndirectYMode, false),
 		// STX
-		0x86: newInstruction("STX", 2, 3, ZeroPageMode),
-		0x96: newInstruction("STX", 2, 4, ZeroPageYMode),
-		0x8E: newInstruction("STX", 3, 4, AbsoluteMode),
+		0x86: newInstruction("STX", (*CPU).STX, 2, 3, ZeroPageMode, false),
+		0x96: newInstruction("STX", (*CPU).STX, 2, 4, ZeroPageYMode, false),
+		0x8E: newInstruction("STX", (*CPU).STX, 3, 4, AbsoluteMode, false),
 		// STY
-		0x84: newInstruction("STY", 2, 3, ZeroPageMode),
-		0x94: newInstruction("STY", 2, 4, ZeroPageXMode),
-		0x8C: newInstruction("STY", 3, 4, AbsoluteMode),
+		0x84: newInstruction("STY", (*CPU).STY, 2, 3, ZeroPageMode, false),
+		0x94: newInstruction("STY", (*CPU).STY, 2, 4, ZeroPageXMode, false),
+		0x8C: newInstruction("STY", (*CPU).STY, 3, 4, AbsoluteMode, false),
 		// TAX
-		0xAA: newInstruction("TAX", 1, 2, ImpliedMode),
+		0xAA: newInstruction("TAX", (*CPU).TAX, 1, 2, ImpliedMode, false),
 		// TAY
-		0xA8: newInstruction("TAY", 1, 2, ImpliedMode),
+		0xA8: newInstruction("TAY", (*CPU).TAY, 1, 2, ImpliedMode, false),
 		// TSX
-		0xBA: newInstruction("TSX", 1, 2, ImpliedMode),
+		0xBA: newInstruction("TSX", (*CPU).TSX, 1, 2, ImpliedMode, false),
 		// TXA
-		0x8A: newInstruction("TXA", 1, 2, ImpliedMode),
+		0x8A: newInstruction("TXA", (*CPU).TXA, 1, 2, ImpliedMode, false),
 		// TXS
-		0x9A: newInstruction("TXS", 1, 2, ImpliedMode),
+		0x9A: newInstruction("TXS", (*CPU).TXS, 1, 2, ImpliedMode, false),
 		// TYA
-		0x98: newInstruction("TYA", 1, 2, ImpliedMode),
+		0x98: newInstruction("TYA", (*CPU).TYA, 1, 2, ImpliedMode, false),
 	}
 }
@@ -0,0 +1,47 @@
+package cpu
+
+// interrupt は割り込みの種類ごとの振る舞い。
+//
+// https://www.nesdev.org/wiki/CPU_interrupts
+type interrupt struct {
+	vector uint16
+	// bFlag はスタックに積むステータスの B フラグ
+	bFlag  bool
+	cycles uint16
+}
+
+var (
+	nmi = interrupt{vector: 0xFF_FA, bFlag: false, cycles: 7}
+	irq = interrupt{vector: 0xFF_FE, bFlag: false, cycles: 7}
+	// BRK のサイクル数は命令として数える
+	brk = interrupt{vector: 0xFF_FE, bFlag: true, cycles: 0}
+)
+
+// pollInterrupts は命令の間で NMI と IRQ を確認し、発生していれば割り込みを処理する。
+// IRQ はレベルトリガーなので、I フラグが下りている間はアサートされ続ける限り発生する。
+func (cpu *CPU) pollInterrupts() {
+	switch {
+	case cpu.Bus.PollNMI():
+		cpu.interrupt(nmi)
+	case cpu.Bus.IRQ() && !cpu.status.i():
+		cpu.interrupt(irq)
+	}
+}
+
+// interrupt は PC とステータスをスタックに積み、割り込みベクタへジャンプする。
+// スタックに積むステータスは bit 5 が常に立ち、B フラグは割り込みの種類で決まる。
+func (cpu *CPU) interrupt(i interrupt) {
+	cpu.tick(i.cycles)
+
+	cpu.pushStackUint16(cpu.ProgramCounter)
+	flags := byte(cpu.status) | 0b0010_0000
+	if i.bFlag {
+		flags |= 0b0001_0000
+	} else {
+		flags &^= 0b0001_0000
+	}
+	cpu.pushStack(flags)
+	cpu.status.setI(true)
+
+	cpu.ProgramCounter = cpu.Bus.ReadMemoryUint16(i.vector)
+}
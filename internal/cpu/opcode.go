@@ -0,0 +1,26 @@
+package cpu
+
+// Opcode は命令表にある命令のメタデータ。逆アセンブラなど CPU の外から命令表を使うためのもの。
+type Opcode struct {
+	Mnemonic   string
+	Mode       AddressingMode
+	Bytes      uint16
+	Cycles     uint16
+	Unofficial bool
+}
+
+// LookupOpcode は code の命令のメタデータを返す。命令表になければ false を返す。
+func LookupOpcode(code byte) (Opcode, bool) {
+	instruction := &instructionTable[code]
+	if instruction.operation == nil {
+		return Opcode{}, false
+	}
+
+	return Opcode{
+		Mnemonic:   instruction.opcode,
+		Mode:       instruction.mode,
+		Bytes:      instruction.bytes,
+		Cycles:     instruction.cycles,
+		Unofficial: instruction.unofficial,
+	}, true
+}
@@ -1,11 +1,10 @@
 package cpu
 
-import "errors"
-
-type addressingMode int
+// AddressingMode は命令のアドレッシングモード。
+type AddressingMode int
 
 const (
-	ImpliedMode addressingMode = iota
+	ImpliedMode AddressingMode = iota
 	AccumulatorMode
 	ImmediateMode
 	ZeroPageMode
@@ -21,33 +20,16 @@ const (
 	NoneAddressingMode
 )
 
-func (cpu *CPU) ADC(mode addressingMode) error {
+func (cpu *CPU) ADC(mode AddressingMode) error {
 	address := cpu.getOperandAddress(mode)
 	value := cpu.Bus.ReadMemory(address)
 
-	var carry byte
-	if cpu.status.c() {
-		carry = 1
-	}
-
-	aSign := uint16(cpu.registerA) & 0b1000_0000
-	vSign := uint16(value) & 0b1000_0000
-	mayOverflow := aSign^vSign == 0b0000_0000
-
-	result := uint16(cpu.registerA) + uint16(value) + uint16(carry)
-
-	rSign := result & 0b1000_0000
-	isDiffSign := rSign != aSign
-	cpu.status.setO(mayOverflow && isDiffSign)
-	cpu.status.setC(result > 0xFF)
-
-	cpu.registerA = byte(result & 0xFF)
-	cpu.updateZeroAndNegativeFlags(cpu.registerA)
+	cpu.add(value)
 
 	return nil
 }
 
-func (cpu *CPU) AND(mode addressingMode) error {
+func (cpu *CPU) AND(mode AddressingMode) error {
 	address := cpu.getOperandAddress(mode)
 	value := cpu.Bus.ReadMemory(address)
 
@@ -58,7 +40,7 @@ func (cpu *CPU) AND(mode addressingMode) error {
 	return nil
 }
 
-func (cpu *CPU) ASL(mode addressingMode) error {
+func (cpu *CPU) ASL(mode AddressingMode) error {
 	var (
 		value   byte
 		address uint16
@@ -66,8 +48,7 @@ func (cpu *CPU) ASL(mode addressingMode) error {
 	if mode == AccumulatorMode {
 		value = cpu.registerA
 	} else {
-		address = cpu.getOperandAddress(mode)
-		value = cpu.Bus.ReadMemory(address)
+		address, value = cpu.readForModify(mode)
 	}
 
 	cpu.status.setC(value&0b1000_0000 != 0)
@@ -79,40 +60,30 @@ func (cpu *CPU) ASL(mode addressingMode) error {
 		cpu.Bus.WriteMemory(address, value)
 	}
 
-	cpu.updateZeroFlag(cpu.registerA)
-	cpu.updateNegativeFlag(value)
+	cpu.updateZeroAndNegativeFlags(value)
 
 	return nil
 }
 
-func (cpu *CPU) BCC(mode addressingMode) error {
-	if !cpu.status.c() {
-		address := cpu.getOperandAddress(mode)
-		cpu.ProgramCounter = address
-	}
+func (cpu *CPU) BCC(mode AddressingMode) error {
+	cpu.branch(mode, !cpu.status.c())
 
 	return nil
 }
 
-func (cpu *CPU) BCS(mode addressingMode) error {
-	if cpu.status.c() {
-		address := cpu.getOperandAddress(mode)
-		cpu.ProgramCounter = address
-	}
+func (cpu *CPU) BCS(mode AddressingMode) error {
+	cpu.branch(mode, cpu.status.c())
 
 	return nil
 }
 
-func (cpu *CPU) BEQ(mode addressingMode) error {
-	if cpu.status.z() {
-		address := cpu.getOperandAddress(mode)
-		cpu.ProgramCounter = address
-	}
+func (cpu *CPU) BEQ(mode AddressingMode) error {
+	cpu.branch(mode, cpu.status.z())
 
 	return nil
 }
 
-func (cpu *CPU) BIT(mode addressingMode) error {
+func (cpu *CPU) BIT(mode AddressingMode) error {
 	address := cpu.getOperandAddress(mode)
 	value := cpu.Bus.ReadMemory(address)
 
@@ -129,116 +100,101 @@ func (cpu *CPU) BIT(mode addressingMode) error {
 	return nil
 }
 
-func (cpu *CPU) BMI(mode addressingMode) error {
-	if cpu.status.n() {
-		address := cpu.getOperandAddress(mode)
-		cpu.ProgramCounter = address
-	}
+func (cpu *CPU) BMI(mode AddressingMode) error {
+	cpu.branch(mode, cpu.status.n())
 
 	return nil
 }
 
-func (cpu *CPU) BNE(mode addressingMode) error {
-	if !cpu.status.z() {
-		address := cpu.getOperandAddress(mode)
-		cpu.ProgramCounter = address
-	}
+func (cpu *CPU) BNE(mode AddressingMode) error {
+	cpu.branch(mode, !cpu.status.z())
 
 	return nil
 }
 
-func (cpu *CPU) BPL(mode addressingMode) error {
-	if !cpu.status.n() {
-		address := cpu.getOperandAddress(mode)
-		cpu.ProgramCounter = address
-	}
+func (cpu *CPU) BPL(mode AddressingMode) error {
+	cpu.branch(mode, !cpu.status.n())
 
 	return nil
 }
 
-func (cpu *CPU) BRK(mode addressingMode) error {
-	return errors.New("BRK called")
+// BRK は 2 バイト目を読み飛ばした番地を戻り先として IRQ と同じベクタへジャンプする。
+func (cpu *CPU) BRK(mode AddressingMode) error {
+	if cpu.HaltOnBRK {
+		return ErrBRK
+	}
+
+	cpu.ProgramCounter++
+	cpu.interrupt(brk)
+
+	return nil
 }
 
-func (cpu *CPU) BVC(mode addressingMode) error {
-	if !cpu.status.o() {
-		address := cpu.getOperandAddress(mode)
-		cpu.ProgramCounter = address
-	}
+func (cpu *CPU) BVC(mode AddressingMode) error {
+	cpu.branch(mode, !cpu.status.o())
 
 	return nil
 }
 
-func (cpu *CPU) BVS(mode addressingMode) error {
-	if cpu.status.o() {
-		address := cpu.getOperandAddress(mode)
-		cpu.ProgramCounter = address
-	}
+func (cpu *CPU) BVS(mode AddressingMode) error {
+	cpu.branch(mode, cpu.status.o())
 
 	return nil
 }
 
-func (cpu *CPU) CLC(mode addressingMode) error {
+func (cpu *CPU) CLC(mode AddressingMode) error {
 	cpu.status.setC(false)
 
 	return nil
 }
 
-func (cpu *CPU) CLD(mode addressingMode) error {
+func (cpu *CPU) CLD(mode AddressingMode) error {
 	cpu.status.setD(false)
 
 	return nil
 }
 
-func (cpu *CPU) CLI(mode addressingMode) error {
+func (cpu *CPU) CLI(mode AddressingMode) error {
 	cpu.status.setI(false)
 
 	return nil
 }
 
-func (cpu *CPU) CLV(mode addressingMode) error {
+func (cpu *CPU) CLV(mode AddressingMode) error {
 	cpu.status.setO(false)
 
 	return nil
 }
 
-func (cpu *CPU) CMP(mode addressingMode) error {
+func (cpu *CPU) CMP(mode AddressingMode) error {
 	address := cpu.getOperandAddress(mode)
 
 	value := cpu.Bus.ReadMemory(address)
-	cpu.status.setZ(cpu.registerA == value)
-	cpu.status.setC(cpu.registerA >= value)
-	cpu.updateNegativeFlag(cpu.registerA - value)
+	cpu.compare(cpu.registerA, value)
 
 	return nil
 }
 
-func (cpu *CPU) CPX(mode addressingMode) error {
+func (cpu *CPU) CPX(mode AddressingMode) error {
 	address := cpu.getOperandAddress(mode)
 
 	value := cpu.Bus.ReadMemory(address)
-	cpu.status.setZ(cpu.registerX == value)
-	cpu.status.setC(cpu.registerX >= value)
-	cpu.updateNegativeFlag(cpu.registerX - value)
+	cpu.compare(cpu.registerX, value)
 
 	return nil
 }
 
-func (cpu *CPU) CPY(mode addressingMode) error {
+func (cpu *CPU) CPY(mode AddressingMode) error {
 	address := cpu.getOperandAddress(mode)
 
 	value := cpu.Bus.ReadMemory(address)
-	cpu.status.setZ(cpu.registerY == value)
-	cpu.status.setC(cpu.registerY >= value)
-	cpu.updateNegativeFlag(cpu.registerY - value)
+	cpu.compare(cpu.registerY, value)
 
 	return nil
 }
 
-func (cpu *CPU) DEC(mode addressingMode) error {
-	address := cpu.getOperandAddress(mode)
-
-	value := cpu.Bus.ReadMemory(address)
+func (cpu *CPU) DEC(mode AddressingMode) error {
+	address, value := cpu.readForModify(mode)
 	value--
 	cpu.Bus.WriteMemory(address, value)
 
@@ -247,21 +203,21 @@ func (cpu *CPU) DEC(mode addressingMode) error {
 	return nil
 }
 
-func (cpu *CPU) DEX(mode addressingMode) error {
+func (cpu *CPU) DEX(mode AddressingMode) error {
 	cpu.registerX--
 	cpu.updateZeroAndNegativeFlags(cpu.registerX)
 
 	return nil
 }
 
-func (cpu *CPU) DEY(mode addressingMode) error {
+func (cpu *CPU) DEY(mode AddressingMode) error {
 	cpu.registerY--
 	cpu.updateZeroAndNegativeFlags(cpu.registerY)
 
 	return nil
 }
 
-func (cpu *CPU) EOR(mode addressingMode) error {
+func (cpu *CPU) EOR(mode AddressingMode) error {
 	address := cpu.getOperandAddress(mode)
 	value := cpu.Bus.ReadMemory(address)
 
@@ -272,10 +228,8 @@ func (cpu *CPU) EOR(mode addressingMode) error {
 	return nil
 }
 
-func (cpu *CPU) INC(mode addressingMode) error {
-	address := cpu.getOperandAddress(mode)
-
-	value := cpu.Bus.ReadMemory(address)
+func (cpu *CPU) INC(mode AddressingMode) error {
+	address, value := cpu.readForModify(mode)
 	value++
 	cpu.Bus.WriteMemory(address, value)
 
@@ -284,21 +238,21 @@ func (cpu *CPU) INC(mode addressingMode) error {
 	return nil
 }
 
-func (cpu *CPU) INX(mode addressingMode) error {
+func (cpu *CPU) INX(mode AddressingMode) error {
 	cpu.registerX++
 	cpu.updateZeroAndNegativeFlags(cpu.registerX)
 
 	return nil
 }
 
-func (cpu *CPU) INY(mode addressingMode) error {
+func (cpu *CPU) INY(mode AddressingMode) error {
 	cpu.registerY++
 	cpu.updateZeroAndNegativeFlags(cpu.registerY)
 
 	return nil
 }
 
-func (cpu *CPU) JMP(mode addressingMode) error {
+func (cpu *CPU) JMP(mode AddressingMode) error {
 	address := cpu.getOperandAddress(mode)
 
 	cpu.ProgramCounter = address - 2
@@ -306,7 +260,7 @@ func (cpu *CPU) JMP(mode addressingMode) error {
 	return nil
 }
 
-func (cpu *CPU) JSR(mode addressingMode) error {
+func (cpu *CPU) JSR(mode AddressingMode) error {
 	address := cpu.getOperandAddress(mode)
 
 	cpu.pushStackUint16(cpu.ProgramCounter + 2 - 1)
@@ -315,7 +269,7 @@ func (cpu *CPU) JSR(mode addressingMode) error {
 	return nil
 }
 
-func (cpu *CPU) LDA(mode addressingMode) error {
+func (cpu *CPU) LDA(mode AddressingMode) error {
 	address := cpu.getOperandAddress(mode)
 	value := cpu.Bus.ReadMemory(address)
 
@@ -325,7 +279,7 @@ func (cpu *CPU) LDA(mode addressingMode) error {
 	return nil
 }
 
-func (cpu *CPU) LDX(mode addressingMode) error {
+func (cpu *CPU) LDX(mode AddressingMode) error {
 	address := cpu.getOperandAddress(mode)
 	value := cpu.Bus.ReadMemory(address)
 
@@ -335,7 +289,7 @@ func (cpu *CPU) LDX(mode addressingMode) error {
 	return nil
 }
 
-func (cpu *CPU) LDY(mode addressingMode) error {
+func (cpu *CPU) LDY(mode AddressingMode) error {
 	address := cpu.getOperandAddress(mode)
 	value := cpu.Bus.ReadMemory(address)
 
@@ -345,7 +299,7 @@ func (cpu *CPU) LDY(mode addressingMode) error {
 	return nil
 }
 
-func (cpu *CPU) LSR(mode addressingMode) error {
+func (cpu *CPU) LSR(mode AddressingMode) error {
 	var (
 		value   byte
 		address uint16
@@ -353,8 +307,7 @@ func (cpu *CPU) LSR(mode addressingMode) error {
 	if mode == AccumulatorMode {
 		value = cpu.registerA
 	} else {
-		address = cpu.getOperandAddress(mode)
-		value = cpu.Bus.ReadMemory(address)
+		address, value = cpu.readForModify(mode)
 	}
 
 	cpu.status.setC(value&0b0000_0001 != 0)
@@ -371,11 +324,16 @@ func (cpu *CPU) LSR(mode addressingMode) error {
 	return nil
 }
 
-func (cpu *CPU) NOP(mode addressingMode) error {
+// NOP はアドレッシングモードを持つ非公式の NOP では、オペランドの番地を読むだけ読む。
+func (cpu *CPU) NOP(mode AddressingMode) error {
+	if mode != ImpliedMode {
+		cpu.Bus.ReadMemory(cpu.getOperandAddress(mode))
+	}
+
 	return nil
 }
 
-func (cpu *CPU) ORA(mode addressingMode) error {
+func (cpu *CPU) ORA(mode AddressingMode) error {
 	address := cpu.getOperandAddress(mode)
 	value := cpu.Bus.ReadMemory(address)
 
@@ -386,32 +344,32 @@ func (cpu *CPU) ORA(mode addressingMode) error {
 	return nil
 }
 
-func (cpu *CPU) PHA(mode addressingMode) error {
+func (cpu *CPU) PHA(mode AddressingMode) error {
 	cpu.pushStack(cpu.registerA)
 
 	return nil
 }
 
-func (cpu *CPU) PHP(mode addressingMode) error {
+func (cpu *CPU) PHP(mode AddressingMode) error {
 	cpu.pushStack(byte(cpu.status))
 
 	return nil
 }
 
-func (cpu *CPU) PLA(mode addressingMode) error {
+func (cpu *CPU) PLA(mode AddressingMode) error {
 	cpu.registerA = cpu.popStack()
 	cpu.updateZeroAndNegativeFlags(cpu.registerA)
 
 	return nil
 }
 
-func (cpu *CPU) PLP(mode addressingMode) error {
+func (cpu *CPU) PLP(mode AddressingMode) error {
 	cpu.status = status(cpu.popStack())
 
 	return nil
 }
 
-func (cpu *CPU) ROL(mode addressingMode) error {
+func (cpu *CPU) ROL(mode AddressingMode) error {
 	var (
 		value   byte
 		address uint16
@@ -419,8 +377,7 @@ func (cpu *CPU) ROL(mode addressingMode) error {
 	if mode == AccumulatorMode {
 		value = cpu.registerA
 	} else {
-		address = cpu.getOperandAddress(mode)
-		value = cpu.Bus.ReadMemory(address)
+		address, value = cpu.readForModify(mode)
 	}
 
 	var new0Bit = 0
@@ -441,7 +398,7 @@ func (cpu *CPU) ROL(mode addressingMode) error {
 	return nil
 }
 
-func (cpu *CPU) ROR(mode addressingMode) error {
+func (cpu *CPU) ROR(mode AddressingMode) error {
 	var (
 		value   byte
 		address uint16
@@ -449,8 +406,7 @@ func (cpu *CPU) ROR(mode addressingMode) error {
 	if mode == AccumulatorMode {
 		value = cpu.registerA
 	} else {
-		address = cpu.getOperandAddress(mode)
-		value = cpu.Bus.ReadMemory(address)
+		address, value = cpu.readForModify(mode)
 	}
 
 	var new7Bit = 0b0000_0000
@@ -466,133 +422,152 @@ func (cpu *CPU) ROR(mode addressingMode) error {
 		cpu.Bus.WriteMemory(address, value)
 	}
 
-	cpu.updateZeroFlag(cpu.registerA)
-	cpu.updateNegativeFlag(value)
+	cpu.updateZeroAndNegativeFlags(value)
 
 	return nil
 }
 
-func (cpu *CPU) RTI(mode addressingMode) error {
+func (cpu *CPU) RTI(mode AddressingMode) error {
 	cpu.status = status(cpu.popStack())
-	cpu.ProgramCounter = cpu.popStackUint16() + 1
+	cpu.ProgramCounter = cpu.popStackUint16()
 
 	return nil
 }
 
-func (cpu *CPU) RTS(mode addressingMode) error {
+func (cpu *CPU) RTS(mode AddressingMode) error {
 	cpu.ProgramCounter = cpu.popStackUint16() + 1
 
 	return nil
 }
 
-func (cpu *CPU) SBC(mode addressingMode) error {
+func (cpu *CPU) SBC(mode AddressingMode) error {
 	address := cpu.getOperandAddress(mode)
 	value := cpu.Bus.ReadMemory(address)
 
-	var carry byte
-	if cpu.status.c() {
-		carry = 1
-	}
-
-	aSign := int16(cpu.registerA) & 0b1000_0000
-	vSign := int16(value) & 0b1000_0000
-	mayOverflow := aSign^vSign == 0b1000_0000
-
-	result := int16(cpu.registerA) - int16(value) - int16(1-carry)
-
-	rSign := result & 0b1000_0000
-	isDiffSign := rSign != aSign
-	isOverflow := mayOverflow && isDiffSign
-	cpu.status.setO(isOverflow)
-	cpu.status.setC(result >= 0)
-
-	cpu.registerA = byte(result & 0xFF)
-	cpu.updateZeroAndNegativeFlags(cpu.registerA)
+	cpu.subtract(value)
 
 	return nil
 }
 
-func (cpu *CPU) SEC(mode addressingMode) error {
+func (cpu *CPU) SEC(mode AddressingMode) error {
 	cpu.status.setC(true)
 
 	return nil
 }
 
-func (cpu *CPU) SED(mode addressingMode) error {
+func (cpu *CPU) SED(mode AddressingMode) error {
 	cpu.status.setD(true)
 
 	return nil
 }
 
-func (cpu *CPU) SEI(mode addressingMode) error {
+func (cpu *CPU) SEI(mode AddressingMode) error {
 	cpu.status.setI(true)
 
 	return nil
 }
 
-func (cpu *CPU) STA(mode addressingMode) error {
-	address := cpu.getOperandAddress(mode)
+func (cpu *CPU) STA(mode AddressingMode) error {
+	address := cpu.getStoreAddress(mode)
 	cpu.Bus.WriteMemory(address, cpu.registerA)
 
 	return nil
 }
 
-func (cpu *CPU) STX(mode addressingMode) error {
-	address := cpu.getOperandAddress(mode)
+func (cpu *CPU) STX(mode AddressingMode) error {
+	address := cpu.getStoreAddress(mode)
 	cpu.Bus.WriteMemory(address, cpu.registerX)
 
 	return nil
 }
 
-func (cpu *CPU) STY(mode addressingMode) error {
-	address := cpu.getOperandAddress(mode)
+func (cpu *CPU) STY(mode AddressingMode) error {
+	address := cpu.getStoreAddress(mode)
 	cpu.Bus.WriteMemory(address, cpu.registerY)
 
 	return nil
 }
 
-func (cpu *CPU) TAX(mode addressingMode) error {
+func (cpu *CPU) TAX(mode AddressingMode) error {
 	cpu.registerX = cpu.registerA
 	cpu.updateZeroAndNegativeFlags(cpu.registerX)
 
 	return nil
 }
 
-func (cpu *CPU) TAY(mode addressingMode) error {
+func (cpu *CPU) TAY(mode AddressingMode) error {
 	cpu.registerY = cpu.registerA
 	cpu.updateZeroAndNegativeFlags(cpu.registerY)
 
 	return nil
 }
 
-func (cpu *CPU) TSX(mode addressingMode) error {
+func (cpu *CPU) TSX(mode AddressingMode) error {
 	cpu.registerX = byte(cpu.stackPointer)
 	cpu.updateZeroAndNegativeFlags(cpu.registerX)
 
 	return nil
 }
 
-func (cpu *CPU) TXA(mode addressingMode) error {
+func (cpu *CPU) TXA(mode AddressingMode) error {
 	cpu.registerA = cpu.registerX
 	cpu.updateZeroAndNegativeFlags(cpu.registerA)
 
 	return nil
 }
 
-func (cpu *CPU) TXS(mode addressingMode) error {
+func (cpu *CPU) TXS(mode AddressingMode) error {
 	cpu.stackPointer = stackPointer(cpu.registerX)
 
 	return nil
 }
 
-func (cpu *CPU) TYA(mode addressingMode) error {
+func (cpu *CPU) TYA(mode AddressingMode) error {
 	cpu.registerA = cpu.registerY
 	cpu.updateZeroAndNegativeFlags(cpu.registerA)
 
 	return nil
 }
 
-func (cpu *CPU) getOperandAddress(mode addressingMode) uint16 {
+// getOperandAddress は読み込み命令のオペランドのアドレスを返す。
+// インデックスを足してページをまたぐと、6502 は上位バイトを直す前のアドレスを一度読む。
+func (cpu *CPU) getOperandAddress(mode AddressingMode) uint16 {
+	address := cpu.effectiveAddress(mode)
+	if cpu.pageCrossed {
+		cpu.Bus.ReadMemory(address - 0x01_00)
+	}
+
+	return address
+}
+
+// getStoreAddress は書き込み命令と RMW 命令のオペランドのアドレスを返す。
+// インデックス付きのモードでは、ページをまたぐかどうかにかかわらず上位バイトを直す前のアドレスを一度読む。
+func (cpu *CPU) getStoreAddress(mode AddressingMode) uint16 {
+	address := cpu.effectiveAddress(mode)
+	switch mode {
+	case AbsoluteXMode, AbsoluteYMode, IndirectYMode:
+		uncorrected := address
+		if cpu.pageCrossed {
+			uncorrected -= 0x01_00
+		}
+		cpu.Bus.ReadMemory(uncorrected)
+	}
+
+	return address
+}
+
+// readForModify は RMW 命令のオペランドのアドレスと値を返す。
+// 6502 は読んだ値を一度そのまま書き戻してから、計算した値を書き込む。
+func (cpu *CPU) readForModify(mode AddressingMode) (uint16, byte) {
+	address := cpu.getStoreAddress(mode)
+	value := cpu.Bus.ReadMemory(address)
+	cpu.Bus.WriteMemory(address, value)
+
+	return address, value
+}
+
+// effectiveAddress はオペランドの実効アドレスを計算する。
+func (cpu *CPU) effectiveAddress(mode AddressingMode) uint16 {
 	switch mode {
 	case ImmediateMode:
 		return cpu.ProgramCounter
@@ -602,12 +577,14 @@ func (cpu *CPU) getOperandAddress(mode addressingMode) uint16 {
 
 	case ZeroPageXMode:
 		position := cpu.Bus.ReadMemory(cpu.ProgramCounter)
+		// ゼロページ内で折り返す
 		address := uint16(position + cpu.registerX)
 
 		return address
 
 	case ZeroPageYMode:
 		position := cpu.Bus.ReadMemory(cpu.ProgramCounter)
+		// ゼロページ内で折り返す
 		address := uint16(position + cpu.registerY)
 
 		return address
@@ -624,36 +601,35 @@ func (cpu *CPU) getOperandAddress(mode addressingMode) uint16 {
 	case AbsoluteXMode:
 		base := cpu.Bus.ReadMemoryUint16(cpu.ProgramCounter)
 		address := base + uint16(cpu.registerX)
+		cpu.pageCrossed = crossesPage(base, address)
 
 		return address
 
 	case AbsoluteYMode:
 		base := cpu.Bus.ReadMemoryUint16(cpu.ProgramCounter)
 		address := base + uint16(cpu.registerY)
+		cpu.pageCrossed = crossesPage(base, address)
 
 		return address
 
 	case IndirectMode:
-		base := cpu.Bus.ReadMemoryUint16(cpu.ProgramCounter)
-		address := cpu.Bus.ReadMemoryUint16(base)
+		pointer := cpu.Bus.ReadMemoryUint16(cpu.ProgramCounter)
+		// ポインタが $xxFF のとき、上位バイトはページをまたがずに $xx00 から読む
+		low := cpu.Bus.ReadMemory(pointer)
+		high := cpu.Bus.ReadMemory(pointer&0xFF_00 | uint16(byte(pointer)+1))
 
-		return address
+		return uint16(high)<<8 | uint16(low)
 
 	case IndirectXMode:
 		base := cpu.Bus.ReadMemory(cpu.ProgramCounter)
-		pointer := base + cpu.registerX
-		low := cpu.Bus.ReadMemory(uint16(pointer))
-		high := cpu.Bus.ReadMemory(uint16(pointer + 1))
-		address := uint16(high)<<8 | uint16(low)
 
-		return address
+		return cpu.readZeroPageUint16(base + cpu.registerX)
 
 	case IndirectYMode:
 		base := cpu.Bus.ReadMemory(cpu.ProgramCounter)
-		low := cpu.Bus.ReadMemory(uint16(base))
-		high := cpu.Bus.ReadMemory(uint16(base + 1))
-		derefBase := uint16(high)<<8 | uint16(low)
+		derefBase := cpu.readZeroPageUint16(base)
 		deref := derefBase + uint16(cpu.registerY)
+		cpu.pageCrossed = crossesPage(derefBase, deref)
 
 		return deref
 
@@ -665,6 +641,61 @@ func (cpu *CPU) getOperandAddress(mode addressingMode) uint16 {
 	}
 }
 
+// readZeroPageUint16 はゼロページのポインタを読む。pointer が $FF なら上位バイトは $00 から読む。
+func (cpu *CPU) readZeroPageUint16(pointer byte) uint16 {
+	low := uint16(cpu.Bus.ReadMemory(uint16(pointer)))
+	high := uint16(cpu.Bus.ReadMemory(uint16(pointer + 1)))
+
+	return high<<8 | low
+}
+
+// addWithCarry は A にキャリー付きで value を加え、C、V、Z、N フラグを更新する。
+func (cpu *CPU) addWithCarry(value byte) {
+	var carry uint16
+	if cpu.status.c() {
+		carry = 1
+	}
+
+	result := uint16(cpu.registerA) + uint16(value) + carry
+	// 同じ符号どうしを足して符号が変わったらオーバーフロー
+	overflow := (cpu.registerA^byte(result))&(value^byte(result))&0b1000_0000 != 0
+	cpu.status.setO(overflow)
+	cpu.status.setC(result > 0xFF)
+
+	cpu.registerA = byte(result)
+	cpu.updateZeroAndNegativeFlags(cpu.registerA)
+}
+
+// compare は register と value を比較して C、Z、N フラグを更新する。
+func (cpu *CPU) compare(register byte, value byte) {
+	cpu.status.setZ(register == value)
+	cpu.status.setC(register >= value)
+	cpu.updateNegativeFlag(register - value)
+}
+
+// branch は condition が真のとき分岐する。
+// 分岐すると 1 サイクル、分岐先が次の命令と別のページならさらに 1 サイクルかかる。
+func (cpu *CPU) branch(mode AddressingMode, condition bool) {
+	if !condition {
+		return
+	}
+
+	address := cpu.getOperandAddress(mode)
+	// 相対アドレスは次の命令の番地を基準にしている
+	next := cpu.ProgramCounter + 1
+	cpu.extraCycles++
+	if crossesPage(next, address+1) {
+		cpu.extraCycles++
+	}
+
+	cpu.ProgramCounter = address
+}
+
+// crossesPage は a と b が別のページ (上位バイト) にあるかどうかを返す。
+func crossesPage(a uint16, b uint16) bool {
+	return a&0xFF_00 != b&0xFF_00
+}
+
 func (cpu *CPU) pushStack(value byte) {
 	cpu.Bus.WriteMemory(cpu.stackPointer.toAddress(), value)
 	cpu.stackPointer--
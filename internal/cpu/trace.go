@@ -0,0 +1,140 @@
+package cpu
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// trace は nestest.log と同じ形式で、これから実行する命令と CPU の状態を w に 1 行書き出す。
+// 非公式命令にはニーモニックの前に * が付く。
+//
+//	C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7
+//
+// https://www.qmtpro.com/~nes/misc/nestest.log
+func (cpu *CPU) trace(w io.Writer) {
+	instruction := &cpu.Instructions[cpu.Bus.PeekMemory(cpu.ProgramCounter)]
+
+	size := instruction.bytes
+	mnemonic := instruction.opcode + cpu.traceOperand(instruction)
+	if instruction.operation == nil {
+		size = 1
+		mnemonic = "???"
+	}
+
+	code := make([]string, size)
+	for i := range size {
+		code[i] = fmt.Sprintf("%02X", cpu.Bus.PeekMemory(cpu.ProgramCounter+i))
+	}
+
+	unofficial := " "
+	if instruction.unofficial {
+		unofficial = "*"
+	}
+
+	scanline, dot := cpu.Bus.PPUPosition()
+
+	fmt.Fprintf(w, "%04X  %-8s %s%-31s A:%02X X:%02X Y:%02X P:%02X SP:%02X PPU:%3d,%3d CYC:%d\n",
+		cpu.ProgramCounter, strings.Join(code, " "), unofficial, mnemonic,
+		cpu.registerA, cpu.registerX, cpu.registerY,
+		// bit 5 は常に 1 として扱う
+		byte(cpu.status)|0b0010_0000, byte(cpu.stackPointer),
+		scanline, dot, cpu.cycles,
+	)
+}
+
+// traceOperand はオペランドを、実効アドレスとそこにある値を含めて文字列にする。
+func (cpu *CPU) traceOperand(instruction *instruction) string {
+	operand := cpu.ProgramCounter + 1
+
+	switch instruction.mode {
+	case AccumulatorMode:
+		return " A"
+
+	case ImmediateMode:
+		return fmt.Sprintf(" #$%02X", cpu.Bus.PeekMemory(operand))
+
+	case ZeroPageMode:
+		address := uint16(cpu.Bus.PeekMemory(operand))
+
+		return fmt.Sprintf(" $%02X = %02X", address, cpu.Bus.PeekMemory(address))
+
+	case ZeroPageXMode:
+		base := cpu.Bus.PeekMemory(operand)
+		address := uint16(base + cpu.registerX)
+
+		return fmt.Sprintf(" $%02X,X @ %02X = %02X", base, address, cpu.Bus.PeekMemory(address))
+
+	case ZeroPageYMode:
+		base := cpu.Bus.PeekMemory(operand)
+		address := uint16(base + cpu.registerY)
+
+		return fmt.Sprintf(" $%02X,Y @ %02X = %02X", base, address, cpu.Bus.PeekMemory(address))
+
+	case RelativeMode:
+		offset := int8(cpu.Bus.PeekMemory(operand))
+
+		return fmt.Sprintf(" $%04X", uint16(int32(operand)+1+int32(offset)))
+
+	case AbsoluteMode:
+		address := cpu.peekUint16(operand)
+		// ジャンプ先の値は意味を持たないので表示しない
+		if instruction.opcode == "JMP" || instruction.opcode == "JSR" {
+			return fmt.Sprintf(" $%04X", address)
+		}
+
+		return fmt.Sprintf(" $%04X = %02X", address, cpu.Bus.PeekMemory(address))
+
+	case AbsoluteXMode:
+		base := cpu.peekUint16(operand)
+		address := base + uint16(cpu.registerX)
+
+		return fmt.Sprintf(" $%04X,X @ %04X = %02X", base, address, cpu.Bus.PeekMemory(address))
+
+	case AbsoluteYMode:
+		base := cpu.peekUint16(operand)
+		address := base + uint16(cpu.registerY)
+
+		return fmt.Sprintf(" $%04X,Y @ %04X = %02X", base, address, cpu.Bus.PeekMemory(address))
+
+	case IndirectMode:
+		pointer := cpu.peekUint16(operand)
+		// 実行時と同じく、上位バイトはページをまたがずに読む
+		low := uint16(cpu.Bus.PeekMemory(pointer))
+		high := uint16(cpu.Bus.PeekMemory(pointer&0xFF_00 | uint16(byte(pointer)+1)))
+
+		return fmt.Sprintf(" ($%04X) = %04X", pointer, high<<8|low)
+
+	case IndirectXMode:
+		base := cpu.Bus.PeekMemory(operand)
+		pointer := base + cpu.registerX
+		address := cpu.peekZeroPageUint16(pointer)
+
+		return fmt.Sprintf(" ($%02X,X) @ %02X = %04X = %02X", base, pointer, address, cpu.Bus.PeekMemory(address))
+
+	case IndirectYMode:
+		base := cpu.Bus.PeekMemory(operand)
+		derefBase := cpu.peekZeroPageUint16(base)
+		address := derefBase + uint16(cpu.registerY)
+
+		return fmt.Sprintf(" ($%02X),Y = %04X @ %04X = %02X", base, derefBase, address, cpu.Bus.PeekMemory(address))
+
+	default:
+		return ""
+	}
+}
+
+func (cpu *CPU) peekUint16(address uint16) uint16 {
+	low := uint16(cpu.Bus.PeekMemory(address))
+	high := uint16(cpu.Bus.PeekMemory(address + 1))
+
+	return high<<8 | low
+}
+
+// peekZeroPageUint16 はゼロページ内で折り返して 2 バイトを読む。
+func (cpu *CPU) peekZeroPageUint16(address byte) uint16 {
+	low := uint16(cpu.Bus.PeekMemory(uint16(address)))
+	high := uint16(cpu.Bus.PeekMemory(uint16(address + 1)))
+
+	return high<<8 | low
+}
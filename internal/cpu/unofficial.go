@@ -0,0 +1,297 @@
+package cpu
+
+// unofficialInstructions は Obelisk のリファレンスにない非公式命令のうち、動作が安定しているもの。
+// 結果が不定な XAA、LXA、AHX、SHX、SHY、TAS は実装していない。
+// JAM (KIL) は Step が JamError として扱う。
+//
+// https://www.nesdev.org/wiki/CPU_unofficial_opcodes
+var unofficialInstructions = map[byte]instruction{
+	// NOP
+	0x1A: newUnofficialInstruction("NOP", (*CPU).NOP, 1, 2, ImpliedMode, false),
+	0x3A: newUnofficialInstruction("NOP", (*CPU).NOP, 1, 2, ImpliedMode, false),
+	0x5A: newUnofficialInstruction("NOP", (*CPU).NOP, 1, 2, ImpliedMode, false),
+	0x7A: newUnofficialInstruction("NOP", (*CPU).NOP, 1, 2, ImpliedMode, false),
+	0xDA: newUnofficialInstruction("NOP", (*CPU).NOP, 1, 2, ImpliedMode, false),
+	0xFA: newUnofficialInstruction("NOP", (*CPU).NOP, 1, 2, ImpliedMode, false),
+	0x80: newUnofficialInstruction("NOP", (*CPU).NOP, 2, 2, ImmediateMode, false),
+	0x82: newUnofficialInstruction("NOP", (*CPU).NOP, 2, 2, ImmediateMode, false),
+	0x89: newUnofficialInstruction("NOP", (*CPU).NOP, 2, 2, ImmediateMode, false),
+	0xC2: newUnofficialInstruction("NOP", (*CPU).NOP, 2, 2, ImmediateMode, false),
+	0xE2: newUnofficialInstruction("NOP", (*CPU).NOP, 2, 2, ImmediateMode, false),
+	0x04: newUnofficialInstruction("NOP", (*CPU).NOP, 2, 3, ZeroPageMode, false),
+	0x44: newUnofficialInstruction("NOP", (*CPU).NOP, 2, 3, ZeroPageMode, false),
+	0x64: newUnofficialInstruction("NOP", (*CPU).NOP, 2, 3, ZeroPageMode, false),
+	0x14: newUnofficialInstruction("NOP", (*CPU).NOP, 2, 4, ZeroPageXMode, false),
+	0x34: newUnofficialInstruction("NOP", (*CPU).NOP, 2, 4, ZeroPageXMode, false),
+	0x54: newUnofficialInstruction("NOP", (*CPU).NOP, 2, 4, ZeroPageXMode, false),
+	0x74: newUnofficialInstruction("NOP", (*CPU).NOP, 2, 4, ZeroPageXMode, false),
+	0xD4: newUnofficialInstruction("NOP", (*CPU).NOP, 2, 4, ZeroPageXMode, false),
+	0xF4: newUnofficialInstruction("NOP", (*CPU).NOP, 2, 4, ZeroPageXMode, false),
+	0x0C: newUnofficialInstruction("NOP", (*CPU).NOP, 3, 4, AbsoluteMode, false),
+	0x1C: newUnofficialInstruction("NOP", (*CPU).NOP, 3, 4, AbsoluteXMode, true),
+	0x3C: newUnofficialInstruction("NOP", (*CPU).NOP, 3, 4, AbsoluteXMode, true),
+	0x5C: newUnofficialInstruction("NOP", (*CPU).NOP, 3, 4, AbsoluteXMode, true),
+	0x7C: newUnofficialInstruction("NOP", (*CPU).NOP, 3, 4, AbsoluteXMode, true),
+	0xDC: newUnofficialInstruction("NOP", (*CPU).NOP, 3, 4, AbsoluteXMode, true),
+	0xFC: newUnofficialInstruction("NOP", (*CPU).NOP, 3, 4, AbsoluteXMode, true),
+	// LAX
+	0xA7: newUnofficialInstruction("LAX", (*CPU).LAX, 2, 3, ZeroPageMode, false),
+	0xB7: newUnofficialInstruction("LAX", (*CPU).LAX, 2, 4, ZeroPageYMode, false),
+	0xAF: newUnofficialInstruction("LAX", (*CPU).LAX, 3, 4, AbsoluteMode, false),
+	0xBF: newUnofficialInstruction("LAX", (*CPU).LAX, 3, 4, AbsoluteYMode, true),
+	0xA3: newUnofficialInstruction("LAX", (*CPU).LAX, 2, 6, IndirectXMode, false),
+	0xB3: newUnofficialInstruction("LAX", (*CPU).LAX, 2, 5, IndirectYMode, true),
+	// SAX
+	0x87: newUnofficialInstruction("SAX", (*CPU).SAX, 2, 3, ZeroPageMode, false),
+	0x97: newUnofficialInstruction("SAX", (*CPU).SAX, 2, 4, ZeroPageYMode, false),
+	0x8F: newUnofficialInstruction("SAX", (*CPU).SAX, 3, 4, AbsoluteMode, false),
+	0x83: newUnofficialInstruction("SAX", (*CPU).SAX, 2, 6, IndirectXMode, false),
+	// SBC
+	0xEB: newUnofficialInstruction("SBC", (*CPU).SBC, 2, 2, ImmediateMode, false),
+	// DCP
+	0xC7: newUnofficialInstruction("DCP", (*CPU).DCP, 2, 5, ZeroPageMode, false),
+	0xD7: newUnofficialInstruction("DCP", (*CPU).DCP, 2, 6, ZeroPageXMode, false),
+	0xCF: newUnofficialInstruction("DCP", (*CPU).DCP, 3, 6, AbsoluteMode, false),
+	0xDF: newUnofficialInstruction("DCP", (*CPU).DCP, 3, 7, AbsoluteXMode, false),
+	0xDB: newUnofficialInstruction("DCP", (*CPU).DCP, 3, 7, AbsoluteYMode, false),
+	0xC3: newUnofficialInstruction("DCP", (*CPU).DCP, 2, 8, IndirectXMode, false),
+	0xD3: newUnofficialInstruction("DCP", (*CPU).DCP, 2, 8, IndirectYMode, false),
+	// ISB
+	0xE7: newUnofficialInstruction("ISB", (*CPU).ISB, 2, 5, ZeroPageMode, false),
+	0xF7: newUnofficialInstruction("ISB", (*CPU).ISB, 2, 6, ZeroPageXMode, false),
+	0xEF: newUnofficialInstruction("ISB", (*CPU).ISB, 3, 6, AbsoluteMode, false),
+	0xFF: newUnofficialInstruction("ISB", (*CPU).ISB, 3, 7, AbsoluteXMode, false),
+	0xFB: newUnofficialInstruction("ISB", (*CPU).ISB, 3, 7, AbsoluteYMode, false),
+	0xE3: newUnofficialInstruction("ISB", (*CPU).ISB, 2, 8, IndirectXMode, false),
+	0xF3: newUnofficialInstruction("ISB", (*CPU).ISB, 2, 8, IndirectYMode, false),
+	// SLO
+	0x07: newUnofficialInstruction("SLO", (*CPU).SLO, 2, 5, ZeroPageMode, false),
+	0x17: newUnofficialInstruction("SLO", (*CPU).SLO, 2, 6, ZeroPageXMode, false),
+	0x0F: newUnofficialInstruction("SLO", (*CPU).SLO, 3, 6, AbsoluteMode, false),
+	0x1F: newUnofficialInstruction("SLO", (*CPU).SLO, 3, 7, AbsoluteXMode, false),
+	0x1B: newUnofficialInstruction("SLO", (*CPU).SLO, 3, 7, AbsoluteYMode, false),
+	0x03: newUnofficialInstruction("SLO", (*CPU).SLO, 2, 8, IndirectXMode, false),
+	0x13: newUnofficialInstruction("SLO", (*CPU).SLO, 2, 8, IndirectYMode, false),
+	// RLA
+	0x27: newUnofficialInstruction("RLA", (*CPU).RLA, 2, 5, ZeroPageMode, false),
+	0x37: newUnofficialInstruction("RLA", (*CPU).RLA, 2, 6, ZeroPageXMode, false),
+	0x2F: newUnofficialInstruction("RLA", (*CPU).RLA, 3, 6, AbsoluteMode, false),
+	0x3F: newUnofficialInstruction("RLA", (*CPU).RLA, 3, 7, AbsoluteXMode, false),
+	0x3B: newUnofficialInstruction("RLA", (*CPU).RLA, 3, 7, AbsoluteYMode, false),
+	0x23: newUnofficialInstruction("RLA", (*CPU).RLA, 2, 8, IndirectXMode, false),
+	0x33: newUnofficialInstruction("RLA", (*CPU).RLA, 2, 8, IndirectYMode, false),
+	// SRE
+	0x47: newUnofficialInstruction("SRE", (*CPU).SRE, 2, 5, ZeroPageMode, false),
+	0x57: newUnofficialInstruction("SRE", (*CPU).SRE, 2, 6, ZeroPageXMode, false),
+	0x4F: newUnofficialInstruction("SRE", (*CPU).SRE, 3, 6, AbsoluteMode, false),
+	0x5F: newUnofficialInstruction("SRE", (*CPU).SRE, 3, 7, AbsoluteXMode, false),
+	0x5B: newUnofficialInstruction("SRE", (*CPU).SRE, 3, 7, AbsoluteYMode, false),
+	0x43: newUnofficialInstruction("SRE", (*CPU).SRE, 2, 8, IndirectXMode, false),
+	0x53: newUnofficialInstruction("SRE", (*CPU).SRE, 2, 8, IndirectYMode, false),
+	// RRA
+	0x67: newUnofficialInstruction("RRA", (*CPU).RRA, 2, 5, ZeroPageMode, false),
+	0x77: newUnofficialInstruction("RRA", (*CPU).RRA, 2, 6, ZeroPageXMode, false),
+	0x6F: newUnofficialInstruction("RRA", (*CPU).RRA, 3, 6, AbsoluteMode, false),
+	0x7F: newUnofficialInstruction("RRA", (*CPU).RRA, 3, 7, AbsoluteXMode, false),
+	0x7B: newUnofficialInstruction("RRA", (*CPU).RRA, 3, 7, AbsoluteYMode, false),
+	0x63: newUnofficialInstruction("RRA", (*CPU).RRA, 2, 8, IndirectXMode, false),
+	0x73: newUnofficialInstruction("RRA", (*CPU).RRA, 2, 8, IndirectYMode, false),
+	// ANC
+	0x0B: newUnofficialInstruction("ANC", (*CPU).ANC, 2, 2, ImmediateMode, false),
+	0x2B: newUnofficialInstruction("ANC", (*CPU).ANC, 2, 2, ImmediateMode, false),
+	// ALR
+	0x4B: newUnofficialInstruction("ALR", (*CPU).ALR, 2, 2, ImmediateMode, false),
+	// ARR
+	0x6B: newUnofficialInstruction("ARR", (*CPU).ARR, 2, 2, ImmediateMode, false),
+	// SBX
+	0xCB: newUnofficialInstruction("SBX", (*CPU).SBX, 2, 2, ImmediateMode, false),
+	// LAS
+	0xBB: newUnofficialInstruction("LAS", (*CPU).LAS, 3, 4, AbsoluteYMode, true),
+}
+
+func newUnofficialInstruction(opcode string, operation operation, bytes uint16, cycles uint16, mode AddressingMode, pageCrossCycle bool) instruction {
+	i := newInstruction(opcode, operation, bytes, cycles, mode, pageCrossCycle)
+	i.unofficial = true
+
+	return i
+}
+
+// instructionTable は公式命令と非公式命令をまとめた命令表。
+var instructionTable = newInstructionTable()
+
+// newInstructionTable は公式命令に非公式命令を加えた命令表を作る。
+func newInstructionTable() [256]instruction {
+	table := NewInstructions()
+	for code, instruction := range unofficialInstructions {
+		table[code] = instruction
+	}
+
+	return table
+}
+
+// LAX は LDA と LDX を同時に行う。
+func (cpu *CPU) LAX(mode AddressingMode) error {
+	address := cpu.getOperandAddress(mode)
+	value := cpu.Bus.ReadMemory(address)
+
+	cpu.registerA = value
+	cpu.registerX = value
+	cpu.updateZeroAndNegativeFlags(value)
+
+	return nil
+}
+
+// SAX は A と X の論理積を書き込む。フラグは変わらない。
+func (cpu *CPU) SAX(mode AddressingMode) error {
+	address := cpu.getStoreAddress(mode)
+	cpu.Bus.WriteMemory(address, cpu.registerA&cpu.registerX)
+
+	return nil
+}
+
+// DCP は DEC と CMP を続けて行う。
+func (cpu *CPU) DCP(mode AddressingMode) error {
+	address, value := cpu.readForModify(mode)
+	value--
+	cpu.Bus.WriteMemory(address, value)
+
+	cpu.compare(cpu.registerA, value)
+
+	return nil
+}
+
+// ISB は INC と SBC を続けて行う。
+func (cpu *CPU) ISB(mode AddressingMode) error {
+	address, value := cpu.readForModify(mode)
+	value++
+	cpu.Bus.WriteMemory(address, value)
+
+	cpu.subtract(value)
+
+	return nil
+}
+
+// SLO は ASL と ORA を続けて行う。
+func (cpu *CPU) SLO(mode AddressingMode) error {
+	address, value := cpu.readForModify(mode)
+	cpu.status.setC(value&0b1000_0000 != 0)
+	value <<= 1
+	cpu.Bus.WriteMemory(address, value)
+
+	cpu.registerA |= value
+	cpu.updateZeroAndNegativeFlags(cpu.registerA)
+
+	return nil
+}
+
+// RLA は ROL と AND を続けて行う。
+func (cpu *CPU) RLA(mode AddressingMode) error {
+	address, value := cpu.readForModify(mode)
+	var carry byte
+	if cpu.status.c() {
+		carry = 0b0000_0001
+	}
+	cpu.status.setC(value&0b1000_0000 != 0)
+	value = value<<1 | carry
+	cpu.Bus.WriteMemory(address, value)
+
+	cpu.registerA &= value
+	cpu.updateZeroAndNegativeFlags(cpu.registerA)
+
+	return nil
+}
+
+// SRE は LSR と EOR を続けて行う。
+func (cpu *CPU) SRE(mode AddressingMode) error {
+	address, value := cpu.readForModify(mode)
+	cpu.status.setC(value&0b0000_0001 != 0)
+	value >>= 1
+	cpu.Bus.WriteMemory(address, value)
+
+	cpu.registerA ^= value
+	cpu.updateZeroAndNegativeFlags(cpu.registerA)
+
+	return nil
+}
+
+// RRA は ROR と ADC を続けて行う。ADC には ROR で押し出されたキャリーが使われる。
+func (cpu *CPU) RRA(mode AddressingMode) error {
+	address, value := cpu.readForModify(mode)
+	var carry byte
+	if cpu.status.c() {
+		carry = 0b1000_0000
+	}
+	cpu.status.setC(value&0b0000_0001 != 0)
+	value = value>>1 | carry
+	cpu.Bus.WriteMemory(address, value)
+
+	cpu.add(value)
+
+	return nil
+}
+
+// ANC は AND の結果の bit 7 を C にも入れる。
+func (cpu *CPU) ANC(mode AddressingMode) error {
+	address := cpu.getOperandAddress(mode)
+	cpu.registerA &= cpu.Bus.ReadMemory(address)
+	cpu.updateZeroAndNegativeFlags(cpu.registerA)
+	cpu.status.setC(cpu.status.n())
+
+	return nil
+}
+
+// ALR は AND と A の LSR を続けて行う。
+func (cpu *CPU) ALR(mode AddressingMode) error {
+	address := cpu.getOperandAddress(mode)
+	value := cpu.registerA & cpu.Bus.ReadMemory(address)
+	cpu.status.setC(value&0b0000_0001 != 0)
+	cpu.registerA = value >> 1
+	cpu.updateZeroAndNegativeFlags(cpu.registerA)
+
+	return nil
+}
+
+// ARR は AND と A の ROR を続けて行う。C は結果の bit 6、V は bit 6 と bit 5 の排他的論理和になる。
+func (cpu *CPU) ARR(mode AddressingMode) error {
+	address := cpu.getOperandAddress(mode)
+	value := cpu.registerA & cpu.Bus.ReadMemory(address)
+	var carry byte
+	if cpu.status.c() {
+		carry = 0b1000_0000
+	}
+	cpu.registerA = value>>1 | carry
+	cpu.updateZeroAndNegativeFlags(cpu.registerA)
+
+	bit6 := cpu.registerA >> 6 & 1
+	bit5 := cpu.registerA >> 5 & 1
+	cpu.status.setC(bit6 == 1)
+	cpu.status.setO(bit6^bit5 == 1)
+
+	return nil
+}
+
+// SBX は A と X の論理積からボローなしで値を引いて X に入れる。
+func (cpu *CPU) SBX(mode AddressingMode) error {
+	address := cpu.getOperandAddress(mode)
+	value := cpu.Bus.ReadMemory(address)
+	and := cpu.registerA & cpu.registerX
+
+	cpu.status.setC(and >= value)
+	cpu.registerX = and - value
+	cpu.updateZeroAndNegativeFlags(cpu.registerX)
+
+	return nil
+}
+
+// LAS は読んだ値と S の論理積を A、X、S に入れる。
+func (cpu *CPU) LAS(mode AddressingMode) error {
+	address := cpu.getOperandAddress(mode)
+	value := cpu.Bus.ReadMemory(address) & byte(cpu.stackPointer)
+
+	cpu.registerA = value
+	cpu.registerX = value
+	cpu.stackPointer = stackPointer(value)
+	cpu.updateZeroAndNegativeFlags(value)
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package disasm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tabo-syu/famicom/internal/cpu"
+)
+
+// Line は逆アセンブルした 1 命令。
+type Line struct {
+	Address uint16
+	Bytes   []byte
+	// Text は `LDA $0200,X` のようなアセンブリ。命令表にないバイトは `.byte $02` になる。
+	Text string
+	// Label はこの番地が分岐先やジャンプ先になっていれば、そのラベル名。
+	Label string
+}
+
+// Memory は逆アセンブルする番地の値を読むためのもの。bus.Bus が満たす。
+type Memory interface {
+	PeekMemory(address uint16) byte
+}
+
+// Disassemble は origin に置かれた code を逆アセンブルする。
+// 範囲内の分岐先とジャンプ先にはラベルを付け、オペランドもラベルで表す。
+func Disassemble(code []byte, origin uint16) []Line {
+	type decoded struct {
+		address uint16
+		bytes   []byte
+		opcode  cpu.Opcode
+		ok      bool
+	}
+
+	var instructions []decoded
+	for offset := 0; offset < len(code); {
+		opcode, ok := cpu.LookupOpcode(code[offset])
+		size := int(opcode.Bytes)
+		// 範囲の終わりで途切れた命令はバイト列として扱う
+		if !ok || offset+size > len(code) {
+			ok = false
+			size = 1
+		}
+
+		instructions = append(instructions, decoded{
+			address: origin + uint16(offset),
+			bytes:   code[offset : offset+size],
+			opcode:  opcode,
+			ok:      ok,
+		})
+		offset += size
+	}
+
+	labels := map[uint16]string{}
+	for _, instruction := range instructions {
+		if target, ok := branchTarget(instruction.address, instruction.bytes, instruction.opcode); ok {
+			labels[target] = fmt.Sprintf("L%04X", target)
+		}
+	}
+	// 命令の途中を指すラベルは出力できないので使わない
+	starts := map[uint16]bool{}
+	for _, instruction := range instructions {
+		starts[instruction.address] = true
+	}
+	for target := range labels {
+		if !starts[target] {
+			delete(labels, target)
+		}
+	}
+
+	lines := make([]Line, len(instructions))
+	for i, instruction := range instructions {
+		text := fmt.Sprintf(".byte $%02X", instruction.bytes[0])
+		if instruction.ok {
+			text = format(instruction.address, instruction.bytes, instruction.opcode, labels)
+		}
+
+		lines[i] = Line{
+			Address: instruction.address,
+			Bytes:   instruction.bytes,
+			Text:    text,
+			Label:   labels[instruction.address],
+		}
+	}
+
+	return lines
+}
+
+// DisassembleMemory は memory の start から end まで (end を含む) を逆アセンブルする。
+// end が start より前なら何も返さない。
+func DisassembleMemory(memory Memory, start uint16, end uint16) []Line {
+	if end < start {
+		return nil
+	}
+
+	code := make([]byte, 0, int(end)-int(start)+1)
+	for address := int(start); address <= int(end); address++ {
+		code = append(code, memory.PeekMemory(uint16(address)))
+	}
+
+	return Disassemble(code, start)
+}
+
+// Write は lines を 1 行ずつ書き出す。ラベルは命令の前の行に書く。
+//
+//	L0300:
+//	0300  A9 01     LDA #$01
+//	0302  D0 FC     BNE L0300
+func Write(w io.Writer, lines []Line) error {
+	for _, line := range lines {
+		if line.Label != "" {
+			if _, err := fmt.Fprintf(w, "%s:\n", line.Label); err != nil {
+				return err
+			}
+		}
+
+		code := make([]string, len(line.Bytes))
+		for i, b := range line.Bytes {
+			code[i] = fmt.Sprintf("%02X", b)
+		}
+		if _, err := fmt.Fprintf(w, "%04X  %-8s  %s\n", line.Address, strings.Join(code, " "), line.Text); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// branchTarget は分岐命令と JMP/JSR (絶対アドレス) の飛び先を返す。
+func branchTarget(address uint16, bytes []byte, opcode cpu.Opcode) (uint16, bool) {
+	switch {
+	case opcode.Mode == cpu.RelativeMode:
+		return address + 2 + uint16(int8(bytes[1])), true
+	case opcode.Mode == cpu.AbsoluteMode && (opcode.Mnemonic == "JMP" || opcode.Mnemonic == "JSR"):
+		return uint16(bytes[2])<<8 | uint16(bytes[1]), true
+	default:
+		return 0, false
+	}
+}
+
+// format は 1 命令をアセンブリの文字列にする。
+func format(address uint16, bytes []byte, opcode cpu.Opcode, labels map[uint16]string) string {
+	if target, ok := branchTarget(address, bytes, opcode); ok {
+		if label, ok := labels[target]; ok {
+			return opcode.Mnemonic + " " + label
+		}
+
+		return fmt.Sprintf("%s $%04X", opcode.Mnemonic, target)
+	}
+
+	var absolute uint16
+	if len(bytes) == 3 {
+		absolute = uint16(bytes[2])<<8 | uint16(bytes[1])
+	}
+
+	var operand string
+	switch opcode.Mode {
+	case cpu.AccumulatorMode:
+		operand = "A"
+	case cpu.ImmediateMode:
+		operand = fmt.Sprintf("#$%02X", bytes[1])
+	case cpu.ZeroPageMode:
+		operand = fmt.Sprintf("$%02X", bytes[1])
+	case cpu.ZeroPageXMode:
+		operand = fmt.Sprintf("$%02X,X", bytes[1])
+	case cpu.ZeroPageYMode:
+		operand = fmt.Sprintf("$%02X,Y", bytes[1])
+	case cpu.AbsoluteMode:
+		operand = fmt.Sprintf("$%04X", absolute)
+	case cpu.AbsoluteXMode:
+		operand = fmt.Sprintf("$%04X,X", absolute)
+	case cpu.AbsoluteYMode:
+		operand = fmt.Sprintf("$%04X,Y", absolute)
+	case cpu.IndirectMode:
+		operand = fmt.Sprintf("($%04X)", absolute)
+	case cpu.IndirectXMode:
+		operand = fmt.Sprintf("($%02X,X)", bytes[1])
+	case cpu.IndirectYMode:
+		operand = fmt.Sprintf("($%02X),Y", bytes[1])
+	default:
+		return opcode.Mnemonic
+	}
+
+	return opcode.Mnemonic + " " + operand
+}
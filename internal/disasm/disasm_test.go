@@ -0,0 +1,91 @@
+package disasm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Disassemble_AddressingModes(t *testing.T) {
+	tests := []struct {
+		name string
+		code []byte
+		want string
+	}{
+		{name: "Implied", code: []byte{0xE8}, want: "INX"},
+		{name: "Accumulator", code: []byte{0x0A}, want: "ASL A"},
+		{name: "Immediate", code: []byte{0xA9, 0x01}, want: "LDA #$01"},
+		{name: "ZeroPage", code: []byte{0xA5, 0x10}, want: "LDA $10"},
+		{name: "ZeroPageX", code: []byte{0xB5, 0x10}, want: "LDA $10,X"},
+		{name: "ZeroPageY", code: []byte{0xB6, 0x10}, want: "LDX $10,Y"},
+		{name: "Absolute", code: []byte{0xAD, 0x00, 0x02}, want: "LDA $0200"},
+		{name: "AbsoluteX", code: []byte{0xBD, 0x00, 0x02}, want: "LDA $0200,X"},
+		{name: "AbsoluteY", code: []byte{0xB9, 0x00, 0x02}, want: "LDA $0200,Y"},
+		{name: "Indirect", code: []byte{0x6C, 0x00, 0x02}, want: "JMP ($0200)"},
+		{name: "IndirectX", code: []byte{0xA1, 0x10}, want: "LDA ($10,X)"},
+		{name: "IndirectY", code: []byte{0xB1, 0x10}, want: "LDA ($10),Y"},
+		// 範囲外への分岐はラベルではなく番地になる
+		{name: "Relative", code: []byte{0xD0, 0x10}, want: "BNE $0612"},
+		{name: "Unofficial", code: []byte{0xA7, 0x10}, want: "LAX $10"},
+		{name: "Unknown", code: []byte{0x02}, want: ".byte $02"},
+		{name: "Truncated", code: []byte{0xAD, 0x00}, want: ".byte $AD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := Disassemble(tt.code, 0x06_00)
+
+			assert.Equal(t, tt.want, lines[0].Text)
+			assert.Equal(t, uint16(0x06_00), lines[0].Address)
+		})
+	}
+}
+
+func Test_Disassemble_Labels(t *testing.T) {
+	code := []byte{
+		0xA2, 0x00, // LDX #$00
+		0xE8,       // loop: INX
+		0xE0, 0x08, // CPX #$08
+		0xD0, 0xFB, // BNE loop
+		0x20, 0x0B, 0x06, // JSR sub
+		0x00, // BRK
+		0x60, // sub: RTS
+	}
+
+	var out strings.Builder
+	assert.NoError(t, Write(&out, Disassemble(code, 0x06_00)))
+
+	want := strings.Join([]string{
+		"0600  A2 00     LDX #$00",
+		"L0602:",
+		"0602  E8        INX",
+		"0603  E0 08     CPX #$08",
+		"0605  D0 FB     BNE L0602",
+		"0607  20 0B 06  JSR L060B",
+		"060A  00        BRK",
+		"L060B:",
+		"060B  60        RTS",
+	}, "\n") + "\n"
+	assert.Equal(t, want, out.String())
+}
+
+type memoryForTest map[uint16]byte
+
+func (m memoryForTest) PeekMemory(address uint16) byte {
+	return m[address]
+}
+
+func Test_DisassembleMemory(t *testing.T) {
+	memory := memoryForTest{0xFF_FE: 0xA9, 0xFF_FF: 0x05}
+
+	lines := DisassembleMemory(memory, 0xFF_FE, 0xFF_FF)
+
+	assert.Equal(t, []Line{{Address: 0xFF_FE, Bytes: []byte{0xA9, 0x05}, Text: "LDA #$05"}}, lines)
+}
+
+func Test_DisassembleMemory_ReversedRange(t *testing.T) {
+	memory := memoryForTest{0x03_00: 0xEA}
+
+	assert.Empty(t, DisassembleMemory(memory, 0x03_01, 0x03_00))
+	assert.Empty(t, DisassembleMemory(memory, 0xFF_FF, 0x00_00))
+}
@@ -1,7 +1,7 @@
 package game
 
 import (
-	"log"
+	"errors"
 	"math/rand"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -15,6 +15,8 @@ type game struct {
 	cpu   *cpu.CPU
 	rng   *rand.Rand
 	board *Board
+	// over はスネークが BRK に到達してゲームオーバーになったかどうか。
+	over bool
 }
 
 func NewGame(cpu *cpu.CPU, rng *rand.Rand) *game {
@@ -27,7 +29,7 @@ func NewGame(cpu *cpu.CPU, rng *rand.Rand) *game {
 
 func (g *game) Update() error {
 	if ebiten.IsKeyPressed(ebiten.KeyEscape) {
-		log.Fatal("Game exited by user")
+		return ebiten.Termination
 	}
 	if inpututil.IsKeyJustPressed(ebiten.KeyW) {
 		g.cpu.Bus.WriteMemory(0xFF, 0x77)
@@ -43,6 +45,18 @@ func (g *game) Update() error {
 	}
 
 	g.cpu.Bus.WriteMemory(0xFE, byte(g.rng.Intn(15)+1))
+
+	// 1 回の Update で CPU を 1/TPS 秒分だけ進める
+	if !g.over {
+		err := g.cpu.RunFor(g.cpu.ClockRate / uint64(ebiten.TPS()))
+		switch {
+		case errors.Is(err, cpu.ErrBRK):
+			g.over = true
+		case err != nil:
+			return err
+		}
+	}
+
 	g.board.Update()
 
 	return nil
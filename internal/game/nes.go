@@ -0,0 +1,54 @@
+package game
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/tabo-syu/famicom/internal/cpu"
+	"github.com/tabo-syu/famicom/internal/ppu"
+)
+
+// nes はカートリッジを実行して PPU の出力を表示する。
+// Update ごとに 1 フレーム分だけエミュレーションを進めるため、CPU を別の goroutine で動かさない。
+type nes struct {
+	cpu    *cpu.CPU
+	screen *Screen
+}
+
+func NewNES(cpu *cpu.CPU, p *ppu.PPU, palette ppu.Palette) *nes {
+	return &nes{
+		cpu:    cpu,
+		screen: NewScreen(p, palette),
+	}
+}
+
+func (n *nes) Update() error {
+	if ebiten.IsKeyPressed(ebiten.KeyEscape) {
+		return ebiten.Termination
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		if n.cpu.Paused() {
+			n.cpu.Resume()
+		} else {
+			n.cpu.Pause()
+		}
+	}
+
+	// CPU と PPU は Update と同じ goroutine で 1 フレーム分だけ進める
+	if !n.cpu.Paused() {
+		if err := n.cpu.RunFrame(); err != nil {
+			return err
+		}
+	}
+
+	n.screen.Update()
+
+	return nil
+}
+
+func (n *nes) Draw(screen *ebiten.Image) {
+	n.screen.Draw(screen)
+}
+
+func (n *nes) Layout(width, height int) (int, int) {
+	return ppu.Width, ppu.Height
+}
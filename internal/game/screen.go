@@ -0,0 +1,39 @@
+package game
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/tabo-syu/famicom/internal/ppu"
+)
+
+// Screen は PPU が描画したフレームを表示する。
+type Screen struct {
+	ppu     *ppu.PPU
+	palette ppu.Palette
+	pixels  []byte
+	image   *ebiten.Image
+}
+
+func NewScreen(p *ppu.PPU, palette ppu.Palette) *Screen {
+	return &Screen{
+		ppu:     p,
+		palette: palette,
+		pixels:  make([]byte, ppu.Width*ppu.Height*4),
+		image:   ebiten.NewImage(ppu.Width, ppu.Height),
+	}
+}
+
+// Update はフレームのピクセル値をパレットで RGBA に変換する。
+func (s *Screen) Update() {
+	for i, pixel := range s.ppu.Frame() {
+		color := s.palette[pixel]
+		s.pixels[i*4] = color.R
+		s.pixels[i*4+1] = color.G
+		s.pixels[i*4+2] = color.B
+		s.pixels[i*4+3] = color.A
+	}
+}
+
+func (s *Screen) Draw(screen *ebiten.Image) {
+	s.image.WritePixels(s.pixels)
+	screen.DrawImage(s.image, nil)
+}
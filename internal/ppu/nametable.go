@@ -0,0 +1,55 @@
+package ppu
+
+import "github.com/tabo-syu/famicom/internal/rom"
+
+// nametablePages はミラーリングごとに、論理ネームテーブル (0x2000/0x2400/0x2800/0x2C00) が
+// VRAM 上の何番目の 1KB を使うかを表す。
+//
+// https://www.nesdev.org/wiki/Mirroring#Nametable_Mirroring
+var nametablePages = map[rom.Mirroring][4]uint16{
+	rom.Horizontal:    {0, 0, 1, 1},
+	rom.Vertical:      {0, 1, 0, 1},
+	rom.SingleScreenA: {0, 0, 0, 0},
+	rom.SingleScreenB: {1, 1, 1, 1},
+	rom.FourScreen:    {0, 1, 2, 3},
+}
+
+const nametableSize = 0x04_00
+
+// nametable は 0x2000-0x2FFF (0x3000-0x3EFF はミラー) のネームテーブルのメモリ。
+// 本体の VRAM は 2KB で、4 画面のカートリッジはさらに 2KB の VRAM を持つ。
+type nametable struct {
+	vram      []byte
+	mirroring rom.Mirroring
+	pages     [4]uint16
+}
+
+func newNametable(mirroring rom.Mirroring) nametable {
+	n := nametable{vram: make([]byte, 2*nametableSize)}
+	n.setMirroring(mirroring)
+
+	return n
+}
+
+func (n *nametable) setMirroring(mirroring rom.Mirroring) {
+	if mirroring == rom.FourScreen && len(n.vram) < 4*nametableSize {
+		n.vram = append(n.vram, make([]byte, 4*nametableSize-len(n.vram))...)
+	}
+
+	n.mirroring = mirroring
+	n.pages = nametablePages[mirroring]
+}
+
+func (n *nametable) index(address uint16) uint16 {
+	offset := (address - Nametables) & 0x0F_FF
+
+	return n.pages[offset/nametableSize]*nametableSize + offset%nametableSize
+}
+
+func (n *nametable) read(address uint16) byte {
+	return n.vram[n.index(address)]
+}
+
+func (n *nametable) write(address uint16, data byte) {
+	n.vram[n.index(address)] = data
+}
@@ -0,0 +1,61 @@
+package ppu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tabo-syu/famicom/internal/rom"
+)
+
+func Test_Nametable_Mirroring(t *testing.T) {
+	tests := []struct {
+		name      string
+		mirroring rom.Mirroring
+		// want は 0x2000/0x2400/0x2800/0x2C00 の 0x05 番地が指す VRAM 上のインデックス
+		want     [4]uint16
+		wantSize int
+	}{
+		{name: "Horizontal", mirroring: rom.Horizontal, want: [4]uint16{0x00_05, 0x00_05, 0x04_05, 0x04_05}, wantSize: 0x08_00},
+		{name: "Vertical", mirroring: rom.Vertical, want: [4]uint16{0x00_05, 0x04_05, 0x00_05, 0x04_05}, wantSize: 0x08_00},
+		{name: "SingleScreenA", mirroring: rom.SingleScreenA, want: [4]uint16{0x00_05, 0x00_05, 0x00_05, 0x00_05}, wantSize: 0x08_00},
+		{name: "SingleScreenB", mirroring: rom.SingleScreenB, want: [4]uint16{0x04_05, 0x04_05, 0x04_05, 0x04_05}, wantSize: 0x08_00},
+		{name: "FourScreen", mirroring: rom.FourScreen, want: [4]uint16{0x00_05, 0x04_05, 0x08_05, 0x0C_05}, wantSize: 0x10_00},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newNametable(tt.mirroring)
+
+			for i, want := range tt.want {
+				address := Nametables + uint16(i)*0x04_00 + 0x05
+				assert.Equal(t, want, n.index(address))
+				// 0x3000-0x3EFF は 0x2000-0x2EFF のミラー
+				assert.Equal(t, want, n.index(address+0x10_00))
+			}
+			assert.Equal(t, tt.wantSize, len(n.vram))
+		})
+	}
+}
+
+func Test_Nametable_SwitchMirroringAtRuntime(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.SetMirroring(rom.SingleScreenA)
+	ppu.writeVRAM(0x2C_10, 0x66)
+	ppu.SetMirroring(rom.SingleScreenB)
+	ppu.writeVRAM(0x20_10, 0x77)
+
+	ppu.SetMirroring(rom.SingleScreenA)
+	assert.Equal(t, byte(0x66), ppu.readVRAM(0x24_10))
+	ppu.SetMirroring(rom.SingleScreenB)
+	assert.Equal(t, byte(0x77), ppu.readVRAM(0x28_10))
+}
+
+func Test_Nametable_FourScreenKeepsEachTable(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.FourScreen)
+	for i := range uint16(4) {
+		ppu.writeVRAM(Nametables+i*0x04_00, byte(i+1))
+	}
+
+	for i := range uint16(4) {
+		assert.Equal(t, byte(i+1), ppu.readVRAM(Nametables+i*0x04_00))
+	}
+}
@@ -0,0 +1,74 @@
+package ppu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tabo-syu/famicom/internal/rom"
+)
+
+func Test_NMI_OnVBlank(t *testing.T) {
+	tests := []struct {
+		name string
+		ctrl byte
+		want bool
+	}{
+		{name: "Enabled", ctrl: 0b1000_0000, want: true},
+		{name: "Disabled", ctrl: 0b0000_0000, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ppu := newPPUForTest([]byte{}, rom.Horizontal)
+			ppu.WriteRegister(0x20_00, tt.ctrl)
+			ppu.stepUntil(vblankScanline, 0)
+			assert.False(t, ppu.PollNMI())
+
+			ppu.step()
+			assert.Equal(t, tt.want, ppu.PollNMI())
+			// 一度受け取った NMI は取り下げられる
+			assert.False(t, ppu.PollNMI())
+		})
+	}
+}
+
+func Test_NMI_EnableDuringVBlank(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.stepUntil(vblankScanline, 10)
+	assert.False(t, ppu.PollNMI())
+
+	ppu.WriteRegister(0x20_00, 0b1000_0000)
+	assert.True(t, ppu.PollNMI())
+
+	// 有効なまま書き込んでも再び発生しない
+	ppu.WriteRegister(0x20_00, 0b1000_0000)
+	assert.False(t, ppu.PollNMI())
+}
+
+// https://www.nesdev.org/wiki/PPU_frame_timing#VBL_Flag_Timing
+func Test_NMI_SuppressByReadingStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		// dot は vblank の開始ライン上で PPUSTATUS を読む直前に処理を終えたドット
+		dot        int
+		wantStatus byte
+		wantVBlank bool
+		wantNMI    bool
+	}{
+		{name: "OneDotBefore", dot: 0, wantStatus: 0x00, wantVBlank: false, wantNMI: false},
+		{name: "SameDot", dot: 1, wantStatus: 0x80, wantVBlank: false, wantNMI: false},
+		{name: "OneDotAfter", dot: 2, wantStatus: 0x80, wantVBlank: false, wantNMI: false},
+		{name: "Later", dot: 3, wantStatus: 0x80, wantVBlank: false, wantNMI: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ppu := newPPUForTest([]byte{}, rom.Horizontal)
+			ppu.WriteRegister(0x20_00, 0b1000_0000)
+			ppu.stepUntil(vblankScanline, tt.dot)
+
+			assert.Equal(t, tt.wantStatus, ppu.ReadRegister(0x20_02)&0x80)
+			ppu.stepUntil(vblankScanline, 10)
+			assert.Equal(t, tt.wantVBlank, ppu.status.vblank())
+			assert.Equal(t, tt.wantNMI, ppu.PollNMI())
+		})
+	}
+}
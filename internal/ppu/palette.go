@@ -0,0 +1,108 @@
+package ppu
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// SystemPalette は 2C02 が出力する 64 色。
+var SystemPalette = [64]color.RGBA{
+	{0x80, 0x80, 0x80, 0xFF}, {0x00, 0x3D, 0xA6, 0xFF}, {0x00, 0x12, 0xB0, 0xFF}, {0x44, 0x00, 0x96, 0xFF},
+	{0xA1, 0x00, 0x5E, 0xFF}, {0xC7, 0x00, 0x28, 0xFF}, {0xBA, 0x06, 0x00, 0xFF}, {0x8C, 0x17, 0x00, 0xFF},
+	{0x5C, 0x2F, 0x00, 0xFF}, {0x10, 0x45, 0x00, 0xFF}, {0x05, 0x4A, 0x00, 0xFF}, {0x00, 0x47, 0x2E, 0xFF},
+	{0x00, 0x41, 0x66, 0xFF}, {0x00, 0x00, 0x00, 0xFF}, {0x05, 0x05, 0x05, 0xFF}, {0x05, 0x05, 0x05, 0xFF},
+	{0xC7, 0xC7, 0xC7, 0xFF}, {0x00, 0x77, 0xFF, 0xFF}, {0x21, 0x55, 0xFF, 0xFF}, {0x82, 0x37, 0xFA, 0xFF},
+	{0xEB, 0x2F, 0xB5, 0xFF}, {0xFF, 0x29, 0x50, 0xFF}, {0xFF, 0x22, 0x00, 0xFF}, {0xD6, 0x32, 0x00, 0xFF},
+	{0xC4, 0x62, 0x00, 0xFF}, {0x35, 0x80, 0x00, 0xFF}, {0x05, 0x8F, 0x00, 0xFF}, {0x00, 0x8A, 0x55, 0xFF},
+	{0x00, 0x99, 0xCC, 0xFF}, {0x21, 0x21, 0x21, 0xFF}, {0x09, 0x09, 0x09, 0xFF}, {0x09, 0x09, 0x09, 0xFF},
+	{0xFF, 0xFF, 0xFF, 0xFF}, {0x0F, 0xD7, 0xFF, 0xFF}, {0x69, 0xA2, 0xFF, 0xFF}, {0xD4, 0x80, 0xFF, 0xFF},
+	{0xFF, 0x45, 0xF3, 0xFF}, {0xFF, 0x61, 0x8B, 0xFF}, {0xFF, 0x88, 0x33, 0xFF}, {0xFF, 0x9C, 0x12, 0xFF},
+	{0xFA, 0xBC, 0x20, 0xFF}, {0x9F, 0xE3, 0x0E, 0xFF}, {0x2B, 0xF0, 0x35, 0xFF}, {0x0C, 0xF0, 0xA4, 0xFF},
+	{0x05, 0xFB, 0xFF, 0xFF}, {0x5E, 0x5E, 0x5E, 0xFF}, {0x0D, 0x0D, 0x0D, 0xFF}, {0x0D, 0x0D, 0x0D, 0xFF},
+	{0xFF, 0xFF, 0xFF, 0xFF}, {0xA6, 0xFC, 0xFF, 0xFF}, {0xB3, 0xEC, 0xFF, 0xFF}, {0xDA, 0xAB, 0xEB, 0xFF},
+	{0xFF, 0xA8, 0xF9, 0xFF}, {0xFF, 0xAB, 0xB3, 0xFF}, {0xFF, 0xD2, 0xB0, 0xFF}, {0xFF, 0xEF, 0xA6, 0xFF},
+	{0xFF, 0xF7, 0x9C, 0xFF}, {0xD7, 0xE8, 0x95, 0xFF}, {0xA6, 0xED, 0xAF, 0xFF}, {0xA2, 0xF2, 0xDA, 0xFF},
+	{0x99, 0xFF, 0xFC, 0xFF}, {0xDD, 0xDD, 0xDD, 0xFF}, {0x11, 0x11, 0x11, 0xFF}, {0x11, 0x11, 0x11, 0xFF},
+}
+
+// Palette は Frame のピクセル値 (カラー番号と強調ビット) から表示する色への変換表。
+// 強調ビットの 8 通りの組み合わせごとに 64 色を持つ。
+type Palette [8 * 64]color.RGBA
+
+// DefaultPalette は SystemPalette に強調ビットによる減衰を適用したパレット。
+var DefaultPalette = NewPalette(SystemPalette)
+
+// emphasisAttenuation は強調されていない色成分に掛かる減衰率。
+const emphasisAttenuation = 0.746
+
+// NewPalette は 64 色から、強調ビットを適用した Palette を作る。
+// 強調ビットが立つと、その色以外の成分が暗くなる。
+//
+// https://www.nesdev.org/wiki/NTSC_video#Color_Tint_Bits
+func NewPalette(colors [64]color.RGBA) Palette {
+	var palette Palette
+
+	for emphasis := range 8 {
+		red, green, blue := 1.0, 1.0, 1.0
+		if emphasis&0b001 != 0 {
+			green *= emphasisAttenuation
+			blue *= emphasisAttenuation
+		}
+		if emphasis&0b010 != 0 {
+			red *= emphasisAttenuation
+			blue *= emphasisAttenuation
+		}
+		if emphasis&0b100 != 0 {
+			red *= emphasisAttenuation
+			green *= emphasisAttenuation
+		}
+
+		for i, c := range colors {
+			// 0xE と 0xF の列は黒なので強調の影響を受けない
+			if i&0x0F >= 0x0E {
+				palette[emphasis*64+i] = c
+
+				continue
+			}
+
+			palette[emphasis*64+i] = color.RGBA{
+				R: byte(float64(c.R) * red),
+				G: byte(float64(c.G) * green),
+				B: byte(float64(c.B) * blue),
+				A: c.A,
+			}
+		}
+	}
+
+	return palette
+}
+
+// LoadPalette は .pal ファイルを読み込む。
+// 64 色 (192 バイト) のファイルには強調ビットの減衰を適用し、
+// 強調ビットの組み合わせごとの 512 色 (1536 バイト) のファイルはそのまま使う。
+func LoadPalette(r io.Reader) (Palette, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Palette{}, err
+	}
+
+	switch len(data) {
+	case 64 * 3:
+		var colors [64]color.RGBA
+		for i := range colors {
+			colors[i] = color.RGBA{data[i*3], data[i*3+1], data[i*3+2], 0xFF}
+		}
+
+		return NewPalette(colors), nil
+	case 8 * 64 * 3:
+		var palette Palette
+		for i := range palette {
+			palette[i] = color.RGBA{data[i*3], data[i*3+1], data[i*3+2], 0xFF}
+		}
+
+		return palette, nil
+	default:
+		return Palette{}, fmt.Errorf("unexpected .pal file size: %d bytes", len(data))
+	}
+}
@@ -0,0 +1,104 @@
+package ppu
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewPalette_Emphasis(t *testing.T) {
+	var colors [64]color.RGBA
+	for i := range colors {
+		colors[i] = color.RGBA{200, 200, 200, 0xFF}
+	}
+	palette := NewPalette(colors)
+
+	tests := []struct {
+		name     string
+		emphasis int
+		want     color.RGBA
+	}{
+		{name: "None", emphasis: 0b000, want: color.RGBA{200, 200, 200, 0xFF}},
+		{name: "Red", emphasis: 0b001, want: color.RGBA{200, 149, 149, 0xFF}},
+		{name: "Green", emphasis: 0b010, want: color.RGBA{149, 200, 149, 0xFF}},
+		{name: "Blue", emphasis: 0b100, want: color.RGBA{149, 149, 200, 0xFF}},
+		{name: "RedAndGreen", emphasis: 0b011, want: color.RGBA{149, 149, 111, 0xFF}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, palette[tt.emphasis*64+0x01])
+			// 0xE と 0xF の列は強調されない
+			assert.Equal(t, colors[0x0E], palette[tt.emphasis*64+0x0E])
+		})
+	}
+}
+
+func Test_LoadPalette(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		want    color.RGBA
+		wantErr bool
+	}{
+		{name: "64Colors", size: 64 * 3, want: color.RGBA{0x03, 0x04, 0x05, 0xFF}},
+		{name: "512Colors", size: 8 * 64 * 3, want: color.RGBA{0x03, 0x04, 0x05, 0xFF}},
+		{name: "Failure/UnexpectedSize", size: 100, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, tt.size)
+			for i := range data {
+				data[i] = byte(i)
+			}
+
+			got, err := LoadPalette(bytes.NewReader(data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadPalette() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			assert.Equal(t, tt.want, got[0x01])
+		})
+	}
+}
+
+func Test_Render_Greyscale(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0000_1011)
+	ppu.writeVRAM(0x20_00, 0x02)
+	ppu.writeVRAM(0x3F_03, 0x27)
+	ppu.renderForTest()
+
+	assert.Equal(t, uint16(0x20), ppu.pixelForTest(0, 0))
+}
+
+func Test_Render_Emphasis(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b1010_1010)
+	ppu.renderForTest()
+
+	assert.Equal(t, uint16(0b101<<6|0x3F), ppu.pixelForTest(0, 0))
+}
+
+func Test_Render_BackdropFromPaletteAddress(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	// 描画が無効で v がパレットを指していると、その色が出力される
+	ppu.setAddrForTest(0x3F_05)
+	ppu.renderForTest()
+
+	assert.Equal(t, uint16(0x05), ppu.pixelForTest(0, 0))
+}
+
+func Test_PPUDATA_ReadPaletteWithGreyscale(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.WriteRegister(0x20_01, 0b0000_0001)
+	ppu.writeVRAM(0x3F_01, 0x27)
+	ppu.setAddrForTest(0x3F_01)
+
+	assert.Equal(t, byte(0x20), ppu.ReadRegister(0x20_07))
+}
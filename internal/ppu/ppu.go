@@ -0,0 +1,353 @@
+package ppu
+
+import "github.com/tabo-syu/famicom/internal/rom"
+
+const (
+	PatternTables        uint16 = 0x00_00
+	PatternTablesEnd     uint16 = 0x1F_FF
+	Nametables           uint16 = 0x20_00
+	NametablesEnd        uint16 = 0x3E_FF
+	PaletteRAM           uint16 = 0x3F_00
+	PaletteRAMMirrorsEnd uint16 = 0x3F_FF
+)
+
+// 1 フレームは 341 ドット x 262 ライン。
+//
+// https://www.nesdev.org/wiki/PPU_rendering
+const (
+	DotsPerScanline   = 341
+	ScanlinesPerFrame = 262
+
+	vblankScanline    = 241
+	preRenderScanline = 261
+)
+
+// Cartridge は PPU から見たカートリッジ。パターンテーブル (0x0000-0x1FFF) は
+// カートリッジ側にあり、バンク切り替えもマッパーが行う。rom.Mapper が満たす。
+type Cartridge interface {
+	ReadCHR(address uint16) byte
+	WriteCHR(address uint16, data byte)
+}
+
+// PPU は 2C02 をエミュレートする。
+// CPU からは 0x2000-0x2007 の 8 つのレジスタを通してのみ操作される。
+type PPU struct {
+	cartridge Cartridge
+	nametable nametable
+	palette   [0x20]byte
+	oam       [0x1_00]byte
+
+	ctrl    control
+	mask    mask
+	status  status
+	oamAddr byte
+
+	// v は現在の VRAM アドレス、t は一時 VRAM アドレス、x は Fine X scroll。
+	v uint16
+	t uint16
+	x byte
+	// w は PPUSCROLL と PPUADDR が共有する書き込みラッチ。
+	w bool
+	// buffer は PPUDATA の読み込みバッファ。
+	buffer byte
+	// openBus は最後に PPU レジスタのデータバスに乗った値。
+	openBus byte
+
+	// nmi は CPU がまだ受け取っていない NMI。
+	nmi bool
+	// suppressVBlank は vblank の直前に PPUSTATUS が読まれ、このフレームの vblank を立てないことを表す。
+	suppressVBlank bool
+
+	// scanline と dot は次に処理する位置。
+	scanline int
+	dot      int
+	// frameCount は描画を終えたフレームの数。奇数フレームでは 1 ドット省略される。
+	frameCount uint64
+	// background は背景のフェッチで使うラッチとシフトレジスタ。
+	background background
+	// sprites は現在のラインに表示されるスプライト。
+	sprites []sprite
+	frame   Frame
+}
+
+// NewPPU はカートリッジと最初のネームテーブルのミラーリングから PPU を作る。
+func NewPPU(cartridge Cartridge, mirroring rom.Mirroring) *PPU {
+	return &PPU{
+		cartridge: cartridge,
+		nametable: newNametable(mirroring),
+		sprites:   make([]sprite, 0, maxSpritesPerScanline),
+	}
+}
+
+// SetMirroring はネームテーブルのミラーリングを切り替える。
+// バスがマッパーへの書き込みのたびに、マッパーのミラーリングに合わせて呼び出す。
+func (ppu *PPU) SetMirroring(mirroring rom.Mirroring) {
+	ppu.nametable.setMirroring(mirroring)
+}
+
+// Tick は PPU を dots ドット進める。CPU の 1 サイクルは 3 ドットに相当する。
+func (ppu *PPU) Tick(dots uint16) {
+	for range dots {
+		ppu.step()
+	}
+}
+
+// step は現在の位置 (scanline, dot) の処理を行い、1 ドット進める。
+func (ppu *PPU) step() {
+	rendering := ppu.rendering()
+
+	if rendering && (ppu.scanline < Height || ppu.scanline == preRenderScanline) {
+		ppu.fetchBackground()
+		ppu.fetchSprites()
+	}
+
+	switch {
+	case ppu.scanline < Height:
+		if 1 <= ppu.dot && ppu.dot <= Width {
+			ppu.renderPixel(ppu.dot-1, ppu.scanline)
+		}
+		// 実機では 65-256 ドットで次のラインのスプライトを評価する
+		if ppu.dot == Width {
+			if rendering {
+				ppu.evaluateSprites(ppu.scanline)
+			} else {
+				ppu.sprites = ppu.sprites[:0]
+			}
+		}
+	case ppu.scanline == vblankScanline && ppu.dot == 1:
+		if !ppu.suppressVBlank {
+			ppu.status.setVBlank(true)
+			ppu.nmi = ppu.ctrl.generateNMI()
+		}
+		ppu.suppressVBlank = false
+	case ppu.scanline == preRenderScanline && ppu.dot == 1:
+		ppu.status.setVBlank(false)
+		ppu.status.setSpriteZeroHit(false)
+		ppu.status.setSpriteOverflow(false)
+		ppu.sprites = ppu.sprites[:0]
+	}
+
+	// 描画中の奇数フレームではプリレンダーラインの最後のドットを飛ばす
+	if ppu.scanline == preRenderScanline && ppu.dot == DotsPerScanline-2 && ppu.rendering() && ppu.frameCount%2 == 1 {
+		ppu.dot++
+	}
+
+	ppu.dot++
+	if ppu.dot < DotsPerScanline {
+		return
+	}
+
+	ppu.dot = 0
+	ppu.scanline++
+	if ppu.scanline == ScanlinesPerFrame {
+		ppu.scanline = 0
+		ppu.frameCount++
+	}
+}
+
+// FrameCount は描き終えたフレームの数を返す。
+// vblank に入った時点でそのフレームは描き終えている。
+func (ppu *PPU) FrameCount() uint64 {
+	if ppu.scanline > vblankScanline || ppu.scanline == vblankScanline && ppu.dot > 1 {
+		return ppu.frameCount + 1
+	}
+
+	return ppu.frameCount
+}
+
+// PollNMI は vblank の開始で NMI が発生したかどうかを返す。
+// CPU が受け取った NMI は取り下げる。
+func (ppu *PPU) PollNMI() bool {
+	nmi := ppu.nmi
+	ppu.nmi = false
+
+	return nmi
+}
+
+// rendering は背景かスプライトの描画が有効かどうかを返す。
+func (ppu *PPU) rendering() bool {
+	return ppu.mask.showBackground() || ppu.mask.showSprites()
+}
+
+// ReadRegister は CPU から 0x2000-0x3FFF への読み込みを処理する。
+// レジスタは 8 バイトごとにミラーされている。
+func (ppu *PPU) ReadRegister(address uint16) byte {
+	switch address & 0b0000_0111 {
+	case 0x02: // PPUSTATUS
+		// vblank が立つ 1 ドット前に読むと、そのフレームでは vblank も NMI も起きない。
+		// 立ったのと同じドットか 1 ドット後に読むと、vblank は読めるが NMI は起きない。
+		//
+		// https://www.nesdev.org/wiki/PPU_frame_timing#VBL_Flag_Timing
+		if ppu.scanline == vblankScanline {
+			switch ppu.dot {
+			case 1:
+				ppu.suppressVBlank = true
+			case 2, 3:
+				ppu.nmi = false
+			}
+		}
+
+		ppu.openBus = byte(ppu.status)&0b1110_0000 | ppu.openBus&0b0001_1111
+		ppu.status.setVBlank(false)
+		ppu.w = false
+	case 0x04: // OAMDATA
+		ppu.openBus = ppu.oam[ppu.oamAddr]
+	case 0x07: // PPUDATA
+		ppu.openBus = ppu.readData()
+	}
+
+	// 書き込み専用レジスタの読み込みは、最後にバスに乗った値が返る
+	return ppu.openBus
+}
+
+// PeekRegister は ReadRegister と同じ値を、フラグやアドレスを変えずに返す。
+// PPUDATA は読み込みバッファの値を返す。
+func (ppu *PPU) PeekRegister(address uint16) byte {
+	switch address & 0b0000_0111 {
+	case 0x02: // PPUSTATUS
+		return byte(ppu.status)&0b1110_0000 | ppu.openBus&0b0001_1111
+	case 0x04: // OAMDATA
+		return ppu.oam[ppu.oamAddr]
+	case 0x07: // PPUDATA
+		return ppu.buffer
+	default:
+		return ppu.openBus
+	}
+}
+
+// Position は次に処理するスキャンラインとドットを返す。
+func (ppu *PPU) Position() (scanline int, dot int) {
+	return ppu.scanline, ppu.dot
+}
+
+// WriteRegister は CPU から 0x2000-0x3FFF への書き込みを処理する。
+// レジスタは 8 バイトごとにミラーされている。
+func (ppu *PPU) WriteRegister(address uint16, data byte) {
+	ppu.openBus = data
+
+	switch address & 0b0000_0111 {
+	case 0x00: // PPUCTRL
+		// vblank 中に NMI を有効にすると、その時点で NMI が発生する
+		if !ppu.ctrl.generateNMI() && control(data).generateNMI() && ppu.status.vblank() {
+			ppu.nmi = true
+		}
+		ppu.ctrl = control(data)
+		ppu.t = ppu.t&^nametableMask | uint16(data&0b0000_0011)<<10
+	case 0x01: // PPUMASK
+		ppu.mask = mask(data)
+	case 0x02: // PPUSTATUS (read only)
+	case 0x03: // OAMADDR
+		ppu.oamAddr = data
+	case 0x04: // OAMDATA
+		ppu.oam[ppu.oamAddr] = data
+		ppu.oamAddr++
+	case 0x05: // PPUSCROLL
+		if !ppu.w {
+			ppu.t = ppu.t&^coarseXMask | uint16(data>>3)
+			ppu.x = data & 0b0000_0111
+		} else {
+			ppu.t = ppu.t&^(fineYMask|coarseYMask) | uint16(data&0b0000_0111)<<12 | uint16(data>>3)<<5
+		}
+		ppu.w = !ppu.w
+	case 0x06: // PPUADDR
+		if !ppu.w {
+			// 最上位ビット (bit 14) はクリアされる
+			ppu.t = ppu.t&0x00_FF | uint16(data&0b0011_1111)<<8
+		} else {
+			ppu.t = ppu.t&0xFF_00 | uint16(data)
+			ppu.v = ppu.t
+		}
+		ppu.w = !ppu.w
+	case 0x07: // PPUDATA
+		ppu.writeData(data)
+	}
+}
+
+// WriteOAMDMA は OAMDMA (0x4014) で転送された 256 バイトを OAMADDR から順に書き込む。
+func (ppu *PPU) WriteOAMDMA(data [0x1_00]byte) {
+	for _, d := range data {
+		ppu.oam[ppu.oamAddr] = d
+		ppu.oamAddr++
+	}
+}
+
+func (ppu *PPU) readData() byte {
+	address := ppu.v & 0x3F_FF
+	ppu.incrementAddr()
+
+	// パレットは読み込みバッファを経由せずに返るが、
+	// バッファにはその下にあるネームテーブルの値が入る
+	if address >= PaletteRAM {
+		ppu.buffer = ppu.readVRAM(address - 0x10_00)
+
+		data := ppu.readVRAM(address)
+		if ppu.mask.greyscale() {
+			data &= 0b0011_0000
+		}
+
+		// パレットは 6 ビットなので、上位 2 ビットには open bus が残る
+		return ppu.openBus&0b1100_0000 | data
+	}
+
+	data := ppu.buffer
+	ppu.buffer = ppu.readVRAM(address)
+
+	return data
+}
+
+func (ppu *PPU) writeData(data byte) {
+	ppu.writeVRAM(ppu.v, data)
+	ppu.incrementAddr()
+}
+
+// incrementAddr は PPUDATA のアクセス後に v を進める。
+// 描画中は通常のインクリメントではなく、横と縦のインクリメントが同時に起きる。
+func (ppu *PPU) incrementAddr() {
+	if ppu.rendering() && (ppu.scanline < Height || ppu.scanline == preRenderScanline) {
+		ppu.incrementX()
+		ppu.incrementY()
+
+		return
+	}
+
+	ppu.v = (ppu.v + ppu.ctrl.vramIncrement()) & 0x7F_FF
+}
+
+// readVRAM は PPU のアドレス空間 (0x0000-0x3FFF) から読み込む。
+func (ppu *PPU) readVRAM(address uint16) byte {
+	address &= 0x3F_FF
+
+	switch {
+	case address <= PatternTablesEnd:
+		return ppu.cartridge.ReadCHR(address)
+	case address <= NametablesEnd:
+		return ppu.nametable.read(address)
+	default:
+		return ppu.palette[mirrorPalette(address)]
+	}
+}
+
+// writeVRAM は PPU のアドレス空間 (0x0000-0x3FFF) に書き込む。
+func (ppu *PPU) writeVRAM(address uint16, data byte) {
+	address &= 0x3F_FF
+
+	switch {
+	case address <= PatternTablesEnd:
+		ppu.cartridge.WriteCHR(address, data)
+	case address <= NametablesEnd:
+		ppu.nametable.write(address, data)
+	default:
+		ppu.palette[mirrorPalette(address)] = data & 0b0011_1111
+	}
+}
+
+// mirrorPalette は 0x3F00-0x3FFF をパレット RAM 上のインデックスに変換する。
+// 0x3F10/0x3F14/0x3F18/0x3F1C は 0x3F00/0x3F04/0x3F08/0x3F0C のミラー。
+func mirrorPalette(address uint16) uint16 {
+	index := address & 0x1F
+	if index&0b0001_0011 == 0b0001_0000 {
+		index &^= 0b0001_0000
+	}
+
+	return index
+}
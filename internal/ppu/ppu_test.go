@@ -0,0 +1,191 @@
+package ppu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tabo-syu/famicom/internal/rom"
+)
+
+// newPPUForTest は chr を CHR ROM に持つ NROM のカートリッジをつないだ PPU を作る。chr が空なら CHR RAM になる。
+func newPPUForTest(chr []byte, mirroring rom.Mirroring) *PPU {
+	mapper, err := rom.NewMapper(&rom.ROM{Chr: chr, ScreenMirroring: mirroring})
+	if err != nil {
+		panic(err)
+	}
+
+	return NewPPU(mapper, mirroring)
+}
+
+func (ppu *PPU) setAddrForTest(address uint16) {
+	ppu.WriteRegister(0x20_06, byte(address>>8))
+	ppu.WriteRegister(0x20_06, byte(address&0x00_FF))
+}
+
+func Test_PPUDATA_WriteAndBufferedRead(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.setAddrForTest(0x23_05)
+	ppu.WriteRegister(0x20_07, 0x66)
+	ppu.WriteRegister(0x20_07, 0x77)
+
+	ppu.setAddrForTest(0x23_05)
+	// 最初の読み込みはバッファの古い値を返す
+	ppu.ReadRegister(0x20_07)
+
+	assert.Equal(t, byte(0x66), ppu.ReadRegister(0x20_07))
+	assert.Equal(t, byte(0x77), ppu.ReadRegister(0x20_07))
+}
+
+func Test_PPUDATA_ReadCHRROM(t *testing.T) {
+	chr := make([]byte, rom.ChrROMPageSize)
+	chr[0x01_23] = 0x45
+	ppu := newPPUForTest(chr, rom.Horizontal)
+	ppu.setAddrForTest(0x01_23)
+	ppu.ReadRegister(0x20_07)
+
+	assert.Equal(t, byte(0x45), ppu.ReadRegister(0x20_07))
+}
+
+func Test_PPUDATA_IgnoreWriteToCHRROM(t *testing.T) {
+	ppu := newPPUForTest(make([]byte, rom.ChrROMPageSize), rom.Horizontal)
+	ppu.setAddrForTest(0x00_10)
+	ppu.WriteRegister(0x20_07, 0x55)
+	ppu.setAddrForTest(0x00_10)
+	ppu.ReadRegister(0x20_07)
+
+	assert.Equal(t, byte(0x00), ppu.ReadRegister(0x20_07))
+}
+
+func Test_PPUDATA_WriteToCHRRAM(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.setAddrForTest(0x00_10)
+	ppu.WriteRegister(0x20_07, 0x55)
+	ppu.setAddrForTest(0x00_10)
+	ppu.ReadRegister(0x20_07)
+
+	assert.Equal(t, byte(0x55), ppu.ReadRegister(0x20_07))
+}
+
+func Test_PPUDATA_Increment32(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.WriteRegister(0x20_00, 0b0000_0100)
+	ppu.setAddrForTest(0x21_FF)
+	ppu.WriteRegister(0x20_07, 0x66)
+	ppu.WriteRegister(0x20_07, 0x77)
+
+	assert.Equal(t, uint16(0x22_3F), ppu.v)
+	assert.Equal(t, byte(0x66), ppu.nametable.vram[0x01_FF])
+	assert.Equal(t, byte(0x77), ppu.nametable.vram[0x02_1F])
+}
+
+func Test_PPUDATA_ReadPaletteWithoutBuffer(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.setAddrForTest(0x2F_01)
+	ppu.WriteRegister(0x20_07, 0x12)
+	ppu.setAddrForTest(0x3F_01)
+	ppu.WriteRegister(0x20_07, 0x21)
+	ppu.setAddrForTest(0x3F_01)
+
+	assert.Equal(t, byte(0x21), ppu.ReadRegister(0x20_07))
+	// バッファにはパレットの下にあるネームテーブルが入る
+	assert.Equal(t, byte(0x12), ppu.buffer)
+}
+
+func Test_PPUDATA_PaletteMirrors(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.setAddrForTest(0x3F_10)
+	ppu.WriteRegister(0x20_07, 0x0F)
+	ppu.setAddrForTest(0x3F_20)
+
+	assert.Equal(t, byte(0x0F), ppu.ReadRegister(0x20_07))
+	assert.Equal(t, byte(0x0F), ppu.palette[0x00])
+}
+
+func Test_PPUDATA_HorizontalMirroring(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.setAddrForTest(0x24_05)
+	ppu.WriteRegister(0x20_07, 0x66)
+	ppu.setAddrForTest(0x28_05)
+	ppu.WriteRegister(0x20_07, 0x77)
+
+	ppu.setAddrForTest(0x20_05)
+	ppu.ReadRegister(0x20_07)
+	assert.Equal(t, byte(0x66), ppu.ReadRegister(0x20_07))
+
+	ppu.setAddrForTest(0x2C_05)
+	ppu.ReadRegister(0x20_07)
+	assert.Equal(t, byte(0x77), ppu.ReadRegister(0x20_07))
+}
+
+func Test_PPUDATA_VerticalMirroring(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Vertical)
+	ppu.setAddrForTest(0x20_05)
+	ppu.WriteRegister(0x20_07, 0x66)
+	ppu.setAddrForTest(0x2C_05)
+	ppu.WriteRegister(0x20_07, 0x77)
+
+	ppu.setAddrForTest(0x28_05)
+	ppu.ReadRegister(0x20_07)
+	assert.Equal(t, byte(0x66), ppu.ReadRegister(0x20_07))
+
+	ppu.setAddrForTest(0x24_05)
+	ppu.ReadRegister(0x20_07)
+	assert.Equal(t, byte(0x77), ppu.ReadRegister(0x20_07))
+}
+
+func Test_PPUADDR_MirrorDownAddress(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.setAddrForTest(0x63_05)
+
+	assert.Equal(t, uint16(0x23_05), ppu.v)
+}
+
+func Test_PPUSTATUS_ResetLatch(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.WriteRegister(0x20_06, 0x21)
+	ppu.ReadRegister(0x20_02)
+	ppu.setAddrForTest(0x23_05)
+
+	assert.Equal(t, uint16(0x23_05), ppu.v)
+}
+
+func Test_PPUSTATUS_ClearVBlank(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.status.setVBlank(true)
+
+	assert.Equal(t, byte(0b1000_0000), ppu.ReadRegister(0x20_02)&0b1000_0000)
+	assert.False(t, ppu.status.vblank())
+}
+
+func Test_PPUSCROLL_ShareLatchWithPPUADDR(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.WriteRegister(0x20_05, 0x7D)
+	ppu.WriteRegister(0x20_06, 0x05)
+
+	assert.Equal(t, byte(0x05), ppu.x)
+	assert.Equal(t, uint16(0x00_05), ppu.v)
+	assert.False(t, ppu.w)
+}
+
+func Test_OAMDATA_WriteAndRead(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.WriteRegister(0x20_03, 0x10)
+	ppu.WriteRegister(0x20_04, 0x66)
+	ppu.WriteRegister(0x20_04, 0x77)
+	ppu.WriteRegister(0x20_03, 0x10)
+
+	assert.Equal(t, byte(0x66), ppu.ReadRegister(0x20_04))
+	// OAMDATA の読み込みでは OAMADDR は進まない
+	assert.Equal(t, byte(0x66), ppu.ReadRegister(0x20_04))
+	ppu.WriteRegister(0x20_03, 0x11)
+	assert.Equal(t, byte(0x77), ppu.ReadRegister(0x20_04))
+}
+
+func Test_Registers_MirroredEvery8Bytes(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.WriteRegister(0x3F_FE, 0x23)
+	ppu.WriteRegister(0x3F_FE, 0x05)
+	ppu.WriteRegister(0x20_0F, 0x66)
+
+	assert.Equal(t, byte(0x66), ppu.nametable.vram[0x03_05])
+}
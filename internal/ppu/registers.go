@@ -0,0 +1,130 @@
+package ppu
+
+/*
+	PPUCTRL (0x2000) `0bVPHB_SINN`
+	- V: Generate an NMI at the start of vblank
+	- P: PPU master/slave select
+	- H: Sprite size (0: 8x8, 1: 8x16)
+	- B: Background pattern table address (0: 0x0000, 1: 0x1000)
+	- S: Sprite pattern table address for 8x8 sprites (0: 0x0000, 1: 0x1000)
+	- I: VRAM address increment per CPU read/write of PPUDATA (0: 1, 1: 32)
+	- N: Base nametable address (0: 0x2000, 1: 0x2400, 2: 0x2800, 3: 0x2C00)
+
+https://www.nesdev.org/wiki/PPU_registers#PPUCTRL
+*/
+type control byte
+
+func (c control) vramIncrement() uint16 {
+	if c&0b0000_0100 != 0 {
+		return 32
+	}
+
+	return 1
+}
+
+func (c control) spritePatternTable() uint16 {
+	if c&0b0000_1000 != 0 {
+		return 0x10_00
+	}
+
+	return 0x00_00
+}
+
+func (c control) backgroundPatternTable() uint16 {
+	if c&0b0001_0000 != 0 {
+		return 0x10_00
+	}
+
+	return 0x00_00
+}
+
+func (c control) spriteHeight() int {
+	if c&0b0010_0000 != 0 {
+		return 16
+	}
+
+	return 8
+}
+
+func (c control) generateNMI() bool {
+	return c&0b1000_0000 != 0
+}
+
+/*
+	PPUMASK (0x2001) `0bBGRs_bMmG`
+	- B: Emphasize blue
+	- G: Emphasize green
+	- R: Emphasize red
+	- s: Show sprites
+	- b: Show background
+	- M: Show sprites in leftmost 8 pixels of screen
+	- m: Show background in leftmost 8 pixels of screen
+	- G: Greyscale
+
+https://www.nesdev.org/wiki/PPU_registers#PPUMASK
+*/
+type mask byte
+
+func (m mask) greyscale() bool {
+	return m&0b0000_0001 != 0
+}
+
+func (m mask) showBackgroundLeft() bool {
+	return m&0b0000_0010 != 0
+}
+
+func (m mask) showSpritesLeft() bool {
+	return m&0b0000_0100 != 0
+}
+
+func (m mask) showBackground() bool {
+	return m&0b0000_1000 != 0
+}
+
+func (m mask) showSprites() bool {
+	return m&0b0001_0000 != 0
+}
+
+// emphasis は強調ビット `0bBGR` を返す。
+func (m mask) emphasis() byte {
+	return byte(m) >> 5
+}
+
+/*
+	PPUSTATUS (0x2002) `0bVSO._....`
+	- V: Vblank has started
+	- S: Sprite 0 hit
+	- O: Sprite overflow
+	- .: PPU open bus
+
+https://www.nesdev.org/wiki/PPU_registers#PPUSTATUS
+*/
+type status byte
+
+func (s status) vblank() bool {
+	return s&0b1000_0000 != 0
+}
+
+func (s *status) setVBlank(v bool) {
+	if v {
+		*s = *s | 0b1000_0000
+	} else {
+		*s = *s & 0b0111_1111
+	}
+}
+
+func (s *status) setSpriteZeroHit(h bool) {
+	if h {
+		*s = *s | 0b0100_0000
+	} else {
+		*s = *s & 0b1011_1111
+	}
+}
+
+func (s *status) setSpriteOverflow(o bool) {
+	if o {
+		*s = *s | 0b0010_0000
+	} else {
+		*s = *s & 0b1101_1111
+	}
+}
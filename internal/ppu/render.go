@@ -0,0 +1,316 @@
+package ppu
+
+const (
+	Width  = 256
+	Height = 240
+)
+
+// Frame は 1 フレーム分の出力。各ピクセルは `0b0000_000B_GRCC_CCCC`
+//   - C: システムパレットのカラー番号 (0x00-0x3F)
+//   - B/G/R: 描画時の PPUMASK の強調ビット
+//
+// Palette で表示する色に変換する。
+type Frame [Width * Height]uint16
+
+// Frame は最後に描画したフレームを返す。
+func (ppu *PPU) Frame() *Frame {
+	return &ppu.frame
+}
+
+// sprite は OAM の 1 エントリ。
+//
+// https://www.nesdev.org/wiki/PPU_OAM
+type sprite struct {
+	index     int
+	y         byte
+	tile      byte
+	attribute byte
+	x         byte
+
+	// patternLow と patternHigh は 257-320 ドットでフェッチした、次のラインで表示する行のパターン。
+	patternLow  byte
+	patternHigh byte
+}
+
+func (s sprite) palette() byte {
+	return s.attribute & 0b0000_0011
+}
+
+func (s sprite) behindBackground() bool {
+	return s.attribute&0b0010_0000 != 0
+}
+
+func (s sprite) flipHorizontally() bool {
+	return s.attribute&0b0100_0000 != 0
+}
+
+func (s sprite) flipVertically() bool {
+	return s.attribute&0b1000_0000 != 0
+}
+
+// maxSpritesPerScanline は 1 ラインに表示できるスプライトの数。
+const maxSpritesPerScanline = 8
+
+// renderPixel は (x, y) のピクセルを描画し、スプライト 0 ヒットを判定する。
+func (ppu *PPU) renderPixel(x int, y int) {
+	bgPixel := ppu.backgroundPixel(x)
+	bgOpaque := bgPixel&0b0000_0011 != 0
+
+	color := ppu.backdrop()
+	if bgOpaque {
+		color = ppu.readVRAM(PaletteRAM + uint16(bgPixel))
+	}
+
+	if spPixel, s, ok := ppu.spritePixel(x); ok {
+		// 右端のピクセルではスプライト 0 ヒットは起きない
+		if s.index == 0 && bgOpaque && x != Width-1 {
+			ppu.status.setSpriteZeroHit(true)
+		}
+
+		if !bgOpaque || !s.behindBackground() {
+			color = ppu.readVRAM(PaletteRAM + uint16(spPixel))
+		}
+	}
+
+	if ppu.mask.greyscale() {
+		color &= 0b0011_0000
+	}
+
+	ppu.frame[y*Width+x] = uint16(ppu.mask.emphasis())<<6 | uint16(color)
+}
+
+// backdrop は背景もスプライトも不透明でないときの色を返す。
+// 描画が無効な間に v がパレットを指していると、その色がそのまま出力される。
+func (ppu *PPU) backdrop() byte {
+	if !ppu.rendering() && ppu.v&0x3F_00 == PaletteRAM {
+		return ppu.readVRAM(ppu.v)
+	}
+
+	return ppu.readVRAM(PaletteRAM)
+}
+
+// background は背景のタイルのフェッチ結果と、描画用の 16 ビットシフトレジスタ。
+// シフトレジスタの上位 8 ビットが現在のタイル、下位 8 ビットが次のタイル。
+//
+// https://www.nesdev.org/wiki/PPU_rendering
+type background struct {
+	tile        byte
+	attribute   byte
+	patternLow  byte
+	patternHigh byte
+
+	shiftPatternLow    uint16
+	shiftPatternHigh   uint16
+	shiftAttributeLow  uint16
+	shiftAttributeHigh uint16
+}
+
+// load は次のタイルのフェッチ結果をシフトレジスタの下位 8 ビットに入れる。
+func (bg *background) load() {
+	bg.shiftPatternLow = bg.shiftPatternLow&0xFF_00 | uint16(bg.patternLow)
+	bg.shiftPatternHigh = bg.shiftPatternHigh&0xFF_00 | uint16(bg.patternHigh)
+
+	// 属性は 1 タイルの 8 ピクセルで共通なので、ビットを 8 ピクセル分に広げる
+	var low, high uint16
+	if bg.attribute&0b01 != 0 {
+		low = 0x00_FF
+	}
+	if bg.attribute&0b10 != 0 {
+		high = 0x00_FF
+	}
+	bg.shiftAttributeLow = bg.shiftAttributeLow&0xFF_00 | low
+	bg.shiftAttributeHigh = bg.shiftAttributeHigh&0xFF_00 | high
+}
+
+func (bg *background) shift() {
+	bg.shiftPatternLow <<= 1
+	bg.shiftPatternHigh <<= 1
+	bg.shiftAttributeLow <<= 1
+	bg.shiftAttributeHigh <<= 1
+}
+
+// fetchBackground は可視ラインとプリレンダーラインで背景のタイルをフェッチし、v を進める。
+// 1-256 ドットで現在のラインの、321-336 ドットで次のラインの先頭 2 タイルをフェッチする。
+func (ppu *PPU) fetchBackground() {
+	dot := ppu.dot
+	bg := &ppu.background
+
+	if (2 <= dot && dot <= 257) || (321 <= dot && dot <= 337) {
+		bg.shift()
+
+		switch (dot - 1) % 8 {
+		case 0:
+			bg.load()
+			bg.tile = ppu.readVRAM(Nametables | ppu.v&0x0F_FF)
+		case 2:
+			address := Nametables + 0x03_C0 | ppu.v&nametableMask | ppu.coarseY()>>2<<3 | ppu.coarseX()>>2
+			// 属性バイトは 4x4 タイルの領域を 2x2 タイルごとに 2 ビットずつ持つ
+			shift := (ppu.coarseY()&0b10)<<1 | ppu.coarseX()&0b10
+			bg.attribute = ppu.readVRAM(address) >> shift & 0b11
+		case 4:
+			bg.patternLow = ppu.readVRAM(ppu.backgroundPatternAddress())
+		case 6:
+			bg.patternHigh = ppu.readVRAM(ppu.backgroundPatternAddress() + 8)
+		case 7:
+			ppu.incrementX()
+		}
+	}
+
+	switch {
+	case dot == Width:
+		ppu.incrementY()
+	case dot == Width+1:
+		ppu.copyX()
+	case ppu.scanline == preRenderScanline && 280 <= dot && dot <= 304:
+		ppu.copyY()
+	}
+}
+
+func (ppu *PPU) backgroundPatternAddress() uint16 {
+	return ppu.ctrl.backgroundPatternTable() + uint16(ppu.background.tile)*16 + ppu.fineY()
+}
+
+// backgroundPixel は x の背景のパレットインデックス (0x00-0x0F) をシフトレジスタから取り出す。
+// 下位 2 ビットが 0 のときは透明。
+func (ppu *PPU) backgroundPixel(x int) byte {
+	if !ppu.mask.showBackground() || (x < 8 && !ppu.mask.showBackgroundLeft()) {
+		return 0
+	}
+
+	bg := &ppu.background
+	bit := 15 - uint16(ppu.x)
+	pixel := byte(bg.shiftPatternHigh>>bit&0b01)<<1 | byte(bg.shiftPatternLow>>bit&0b01)
+	palette := byte(bg.shiftAttributeHigh>>bit&0b01)<<1 | byte(bg.shiftAttributeLow>>bit&0b01)
+
+	return palette<<2 | pixel
+}
+
+// evaluateSprites は line の次のラインに表示されるスプライトを OAM の順に最大 8 個まで集める。
+// 9 個目が見つかるとスプライトオーバーフローを立てるが、
+// ハードウェアのバグを再現するため 9 個目以降は Y 座標以外のバイトも比較してしまう。
+//
+// https://www.nesdev.org/wiki/PPU_sprite_evaluation
+func (ppu *PPU) evaluateSprites(line int) {
+	ppu.sprites = ppu.sprites[:0]
+	height := ppu.ctrl.spriteHeight()
+	inRange := func(y byte) bool {
+		row := line - int(y)
+
+		return 0 <= row && row < height
+	}
+
+	n := 0
+	for ; n < len(ppu.oam)/4 && len(ppu.sprites) < maxSpritesPerScanline; n++ {
+		if !inRange(ppu.oam[n*4]) {
+			continue
+		}
+
+		ppu.sprites = append(ppu.sprites, sprite{
+			index:     n,
+			y:         ppu.oam[n*4],
+			tile:      ppu.oam[n*4+1],
+			attribute: ppu.oam[n*4+2],
+			x:         ppu.oam[n*4+3],
+		})
+	}
+
+	m := 0
+	for ; n < len(ppu.oam)/4; n++ {
+		if inRange(ppu.oam[n*4+m]) {
+			ppu.status.setSpriteOverflow(true)
+
+			break
+		}
+
+		m = (m + 1) & 0b11
+	}
+}
+
+// fetchSprites は 257-320 ドットで、evaluateSprites で選んだスプライトのパターンを 1 個あたり 8 ドットでフェッチする。
+// スプライトが 8 個に満たなくてもタイル 0xFF を空読みするため、パターンテーブルへのアクセスは毎ライン起きる。
+// MMC3 はこのアクセスでアドレスの A12 が立ち上がるのを数えてラインを数える。
+//
+// https://www.nesdev.org/wiki/PPU_rendering#Cycles_257-320
+func (ppu *PPU) fetchSprites() {
+	if ppu.dot < Width+1 || Width+64 < ppu.dot {
+		return
+	}
+
+	slot := (ppu.dot - Width - 1) / 8
+	var s *sprite
+	if slot < len(ppu.sprites) {
+		s = &ppu.sprites[slot]
+	}
+
+	switch (ppu.dot - Width - 1) % 8 {
+	case 4:
+		data := ppu.readVRAM(ppu.spritePatternAddress(s))
+		if s != nil {
+			s.patternLow = data
+		}
+	case 6:
+		data := ppu.readVRAM(ppu.spritePatternAddress(s) + 8)
+		if s != nil {
+			s.patternHigh = data
+		}
+	}
+}
+
+// spritePatternAddress は s が次のラインで表示する行のパターンテーブル上のアドレスを返す。
+// s が nil なら空読みするタイル 0xFF の先頭行のアドレスを返す。
+func (ppu *PPU) spritePatternAddress(s *sprite) uint16 {
+	height := ppu.ctrl.spriteHeight()
+	tile := byte(0xFF)
+	row := 0
+	if s != nil {
+		tile = s.tile
+		row = ppu.scanline - int(s.y)
+		if s.flipVertically() {
+			row = height - 1 - row
+		}
+	}
+
+	var table uint16
+	if height == 16 {
+		// 8x16 では タイル番号の bit 0 がパターンテーブルを選ぶ
+		table = uint16(tile&0b0000_0001) * 0x10_00
+		tile &= 0b1111_1110
+		if row >= 8 {
+			tile++
+			row -= 8
+		}
+	} else {
+		table = ppu.ctrl.spritePatternTable()
+	}
+
+	return table + uint16(tile)*16 + uint16(row)
+}
+
+// spritePixel は x で最も優先度の高い不透明なスプライトのピクセルを返す。
+// パレットインデックスはスプライトパレット (0x10-0x1F) のもの。
+func (ppu *PPU) spritePixel(x int) (byte, sprite, bool) {
+	if !ppu.mask.showSprites() || (x < 8 && !ppu.mask.showSpritesLeft()) {
+		return 0, sprite{}, false
+	}
+
+	for _, s := range ppu.sprites {
+		column := x - int(s.x)
+		if column < 0 || 8 <= column {
+			continue
+		}
+
+		bit := 7 - byte(column)
+		if s.flipHorizontally() {
+			bit = byte(column)
+		}
+
+		pixel := (s.patternHigh>>bit)&0b01<<1 | (s.patternLow>>bit)&0b01
+		if pixel == 0 {
+			continue
+		}
+
+		return 0x10 | s.palette()<<2 | pixel, s, true
+	}
+
+	return 0, sprite{}, false
+}
@@ -0,0 +1,181 @@
+package ppu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tabo-syu/famicom/internal/rom"
+)
+
+// newPPUForRenderTest はタイル 1 が左上 1 ピクセルだけ色 1、
+// タイル 2 が全面色 3 のパターンを持つ PPU を作る。
+func newPPUForRenderTest() *PPU {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	// タイル 1: 0 行目の左端だけ low プレーンが立つ
+	ppu.writeVRAM(1*16, 0b1000_0000)
+	// タイル 2: 全ピクセルで low/high プレーンが立つ
+	for i := range 16 {
+		ppu.writeVRAM(uint16(2*16+i), 0xFF)
+	}
+	for i := range 0x20 {
+		ppu.palette[i] = byte(i)
+	}
+	ppu.palette[0] = 0x3F
+	// 使わないスプライトは画面外に置く
+	for i := range ppu.oam {
+		ppu.oam[i] = 0xFF
+	}
+
+	return ppu
+}
+
+// renderForTest はプリレンダーラインから 1 フレーム分を描画する。
+func (ppu *PPU) renderForTest() {
+	ppu.scanline = preRenderScanline
+	ppu.dot = 0
+	for ppu.scanline != Height {
+		ppu.step()
+	}
+}
+
+func (ppu *PPU) pixelForTest(x int, y int) uint16 {
+	return ppu.frame[y*Width+x]
+}
+
+func Test_Render_Backdrop(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.renderForTest()
+
+	assert.Equal(t, uint16(0x3F), ppu.pixelForTest(0, 0))
+	assert.Equal(t, uint16(0x3F), ppu.pixelForTest(255, 239))
+}
+
+func Test_Render_BackgroundTile(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0000_1010)
+	// (column 1, row 2) にタイル 1
+	ppu.writeVRAM(0x20_00+2*32+1, 0x01)
+	ppu.renderForTest()
+
+	assert.Equal(t, uint16(0x01), ppu.pixelForTest(8, 16))
+	assert.Equal(t, uint16(0x3F), ppu.pixelForTest(9, 16))
+	assert.Equal(t, uint16(0x3F), ppu.pixelForTest(8, 17))
+}
+
+func Test_Render_BackgroundAttribute(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0000_1010)
+	// (column 2, row 0) は属性バイト 0 の右上 (bit 2-3)
+	ppu.writeVRAM(0x20_00+2, 0x02)
+	ppu.writeVRAM(0x23_C0, 0b0000_1000)
+	ppu.renderForTest()
+
+	assert.Equal(t, uint16(0x0B), ppu.pixelForTest(16, 0))
+}
+
+func Test_Render_BackgroundScroll(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0000_1010)
+	// 横ミラーなので 0x2800 は下のネームテーブル
+	ppu.writeVRAM(0x28_00, 0x01)
+	ppu.WriteRegister(0x20_00, 0b0000_0010)
+	ppu.WriteRegister(0x20_05, 0x00)
+	ppu.WriteRegister(0x20_05, 0x10)
+	ppu.renderForTest()
+
+	// ネームテーブル 2 から 16 ライン下にスクロールすると、0x2800 の先頭行は画面外
+	assert.Equal(t, uint16(0x3F), ppu.pixelForTest(0, 0))
+	ppu.WriteRegister(0x20_05, 0x00)
+	ppu.WriteRegister(0x20_05, 0x00)
+	ppu.renderForTest()
+	assert.Equal(t, uint16(0x01), ppu.pixelForTest(0, 0))
+}
+
+func Test_Render_HideBackgroundLeft(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0000_1000)
+	ppu.writeVRAM(0x20_00, 0x01)
+	ppu.renderForTest()
+
+	assert.Equal(t, uint16(0x3F), ppu.pixelForTest(0, 0))
+}
+
+func Test_Render_Sprite(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0001_0100)
+	copy(ppu.oam[:], []byte{9, 0x01, 0b0000_0001, 20})
+	ppu.renderForTest()
+
+	// Y 座標の 1 ライン下に表示される
+	assert.Equal(t, uint16(0x15), ppu.pixelForTest(20, 10))
+	assert.Equal(t, uint16(0x3F), ppu.pixelForTest(20, 9))
+	assert.Equal(t, uint16(0x3F), ppu.pixelForTest(21, 10))
+}
+
+func Test_Render_SpriteFlip(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0001_0100)
+	copy(ppu.oam[:], []byte{9, 0x01, 0b1100_0000, 20})
+	ppu.renderForTest()
+
+	assert.Equal(t, uint16(0x11), ppu.pixelForTest(27, 17))
+	assert.Equal(t, uint16(0x3F), ppu.pixelForTest(20, 10))
+}
+
+func Test_Render_SpriteBehindBackground(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0001_1110)
+	ppu.writeVRAM(0x20_00, 0x02)
+	copy(ppu.oam[:], []byte{
+		0, 0x02, 0b0010_0000, 0,
+		0, 0x02, 0b0000_0001, 8,
+	})
+	ppu.renderForTest()
+
+	// 背景が不透明なら背景の後ろに隠れる
+	assert.Equal(t, uint16(0x03), ppu.pixelForTest(0, 1))
+	// 背景が透明ならスプライトが見える
+	assert.Equal(t, uint16(0x17), ppu.pixelForTest(8, 1))
+}
+
+func Test_Render_SpritePriorityByOAMIndex(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0001_1110)
+	ppu.writeVRAM(0x20_00, 0x02)
+	// OAM の先頭が背景の後ろでも、後ろのスプライトより優先される
+	copy(ppu.oam[:], []byte{
+		0, 0x02, 0b0010_0000, 0,
+		0, 0x02, 0b0000_0001, 0,
+	})
+	ppu.renderForTest()
+
+	assert.Equal(t, uint16(0x03), ppu.pixelForTest(0, 1))
+}
+
+func Test_Render_Sprite8x16(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0001_0100)
+	ppu.ctrl = control(0b0010_0000)
+	// タイル 0x02 (上) と 0x03 (下)
+	copy(ppu.oam[:], []byte{0, 0x02, 0b0000_0000, 0})
+	ppu.renderForTest()
+
+	assert.Equal(t, uint16(0x13), ppu.pixelForTest(0, 8))
+	assert.Equal(t, uint16(0x3F), ppu.pixelForTest(0, 9))
+}
+
+func Test_Render_MaxSpritesPerScanline(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0001_0100)
+	for i := range 9 {
+		ppu.oam[i*4] = 0
+		ppu.oam[i*4+1] = 0x02
+		ppu.oam[i*4+2] = 0x00
+		ppu.oam[i*4+3] = byte(i * 8)
+	}
+	ppu.renderForTest()
+
+	assert.Equal(t, uint16(0x13), ppu.pixelForTest(56, 1))
+	// 9 個目のスプライトは表示されない
+	assert.Equal(t, uint16(0x3F), ppu.pixelForTest(64, 1))
+}
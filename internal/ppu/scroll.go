@@ -0,0 +1,79 @@
+package ppu
+
+/*
+	v と t は 15 ビットの VRAM アドレス `0byyy_NNYY_YYYX_XXXX`
+	- y: Fine Y scroll
+	- N: Nametable select
+	- Y: Coarse Y scroll
+	- X: Coarse X scroll
+
+	描画中は v がフェッチするタイルを指し、t は次のフレーム (またはライン) の
+	スクロール位置を保持する。x は 3 ビットの Fine X scroll。
+
+https://www.nesdev.org/wiki/PPU_scrolling
+*/
+const (
+	coarseXMask   uint16 = 0b000_00_00000_11111
+	coarseYMask   uint16 = 0b000_00_11111_00000
+	nametableMask uint16 = 0b000_11_00000_00000
+	nametableX    uint16 = 0b000_01_00000_00000
+	nametableY    uint16 = 0b000_10_00000_00000
+	fineYMask     uint16 = 0b111_00_00000_00000
+)
+
+func (ppu *PPU) coarseX() uint16 {
+	return ppu.v & coarseXMask
+}
+
+func (ppu *PPU) coarseY() uint16 {
+	return (ppu.v & coarseYMask) >> 5
+}
+
+func (ppu *PPU) fineY() uint16 {
+	return (ppu.v & fineYMask) >> 12
+}
+
+// incrementX は v を右隣のタイルに進める。右端では横のネームテーブルに切り替わる。
+func (ppu *PPU) incrementX() {
+	if ppu.coarseX() == 31 {
+		ppu.v &^= coarseXMask
+		ppu.v ^= nametableX
+
+		return
+	}
+
+	ppu.v++
+}
+
+// incrementY は v を 1 ライン下に進める。30 行目を越えると縦のネームテーブルに切り替わる。
+func (ppu *PPU) incrementY() {
+	if ppu.fineY() < 7 {
+		ppu.v += 0b001_00_00000_00000
+
+		return
+	}
+
+	ppu.v &^= fineYMask
+	switch y := ppu.coarseY(); y {
+	case 29:
+		ppu.v &^= coarseYMask
+		ppu.v ^= nametableY
+	case 31:
+		// 属性テーブルの位置から溢れた場合はネームテーブルを切り替えない
+		ppu.v &^= coarseYMask
+	default:
+		ppu.v = ppu.v&^coarseYMask | (y+1)<<5
+	}
+}
+
+// copyX は t の横方向の位置を v に写す。
+func (ppu *PPU) copyX() {
+	mask := coarseXMask | nametableX
+	ppu.v = ppu.v&^mask | ppu.t&mask
+}
+
+// copyY は t の縦方向の位置を v に写す。
+func (ppu *PPU) copyY() {
+	mask := fineYMask | nametableY | coarseYMask
+	ppu.v = ppu.v&^mask | ppu.t&mask
+}
@@ -0,0 +1,126 @@
+package ppu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tabo-syu/famicom/internal/rom"
+)
+
+// https://www.nesdev.org/wiki/PPU_scrolling#Summary
+func Test_Scroll_RegisterWrites(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.t = 0b111_11_11111_11111
+
+	ppu.WriteRegister(0x20_00, 0b0000_0000)
+	assert.Equal(t, uint16(0b111_00_11111_11111), ppu.t)
+
+	ppu.ReadRegister(0x20_02)
+	assert.False(t, ppu.w)
+
+	ppu.WriteRegister(0x20_05, 0b0111_1101)
+	assert.Equal(t, uint16(0b111_00_11111_01111), ppu.t)
+	assert.Equal(t, byte(0b101), ppu.x)
+	assert.True(t, ppu.w)
+
+	ppu.WriteRegister(0x20_05, 0b0101_1110)
+	assert.Equal(t, uint16(0b110_00_01011_01111), ppu.t)
+	assert.False(t, ppu.w)
+
+	ppu.WriteRegister(0x20_06, 0b0011_1101)
+	assert.Equal(t, uint16(0b011_11_01011_01111), ppu.t)
+	assert.True(t, ppu.w)
+
+	ppu.WriteRegister(0x20_06, 0b1111_0000)
+	assert.Equal(t, uint16(0b011_11_01111_10000), ppu.t)
+	assert.Equal(t, ppu.t, ppu.v)
+	assert.False(t, ppu.w)
+}
+
+func Test_Scroll_IncrementX(t *testing.T) {
+	tests := []struct {
+		name string
+		v    uint16
+		want uint16
+	}{
+		{name: "Increment", v: 0b000_00_00000_00001, want: 0b000_00_00000_00010},
+		{name: "SwitchNametable", v: 0b000_00_00000_11111, want: 0b000_01_00000_00000},
+		{name: "SwitchNametableBack", v: 0b000_01_00000_11111, want: 0b000_00_00000_00000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ppu := newPPUForTest([]byte{}, rom.Horizontal)
+			ppu.v = tt.v
+			ppu.incrementX()
+
+			assert.Equal(t, tt.want, ppu.v)
+		})
+	}
+}
+
+func Test_Scroll_IncrementY(t *testing.T) {
+	tests := []struct {
+		name string
+		v    uint16
+		want uint16
+	}{
+		{name: "IncrementFineY", v: 0b010_00_00001_00000, want: 0b011_00_00001_00000},
+		{name: "IncrementCoarseY", v: 0b111_00_00001_00000, want: 0b000_00_00010_00000},
+		{name: "SwitchNametable", v: 0b111_00_11101_00000, want: 0b000_10_00000_00000},
+		{name: "WrapWithoutSwitchingNametable", v: 0b111_00_11111_00000, want: 0b000_00_00000_00000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ppu := newPPUForTest([]byte{}, rom.Horizontal)
+			ppu.v = tt.v
+			ppu.incrementY()
+
+			assert.Equal(t, tt.want, ppu.v)
+		})
+	}
+}
+
+func Test_Scroll_FineX(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0000_1010)
+	ppu.writeVRAM(0x20_01, 0x01)
+	ppu.WriteRegister(0x20_05, 0x03)
+	ppu.WriteRegister(0x20_05, 0x00)
+	ppu.renderForTest()
+
+	assert.Equal(t, uint16(0x01), ppu.pixelForTest(5, 0))
+	assert.Equal(t, uint16(0x3F), ppu.pixelForTest(8, 0))
+}
+
+func Test_Scroll_HorizontalNametable(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0000_1010)
+	// 縦ミラーでは 0x2400 が右隣のネームテーブル
+	ppu.SetMirroring(rom.Vertical)
+	ppu.writeVRAM(0x24_00, 0x01)
+	ppu.WriteRegister(0x20_05, 0x08)
+	ppu.WriteRegister(0x20_05, 0x00)
+	ppu.renderForTest()
+
+	assert.Equal(t, uint16(0x01), ppu.pixelForTest(Width-8, 0))
+}
+
+func Test_Scroll_MidFrameSplit(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0000_1010)
+	for row := range uint16(30) {
+		ppu.writeVRAM(0x20_00+row*32, 0x02)
+	}
+	ppu.scanline = preRenderScanline
+	ppu.dot = 0
+
+	// 100 ライン目の描画中に X 方向へ 1 タイル分スクロールする
+	ppu.stepUntil(100, 200)
+	ppu.WriteRegister(0x20_05, 0x08)
+	ppu.WriteRegister(0x20_05, 0x00)
+	ppu.stepUntil(Height-1, Width)
+
+	assert.Equal(t, uint16(0x03), ppu.pixelForTest(0, 100))
+	assert.Equal(t, uint16(0x3F), ppu.pixelForTest(0, 101))
+	assert.Equal(t, uint16(0x3F), ppu.pixelForTest(0, Height-1))
+}
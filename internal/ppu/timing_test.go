@@ -0,0 +1,194 @@
+package ppu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tabo-syu/famicom/internal/rom"
+)
+
+// stepUntil は (scanline, dot) の処理を終えるところまで PPU を進める。
+func (ppu *PPU) stepUntil(scanline int, dot int) {
+	for ppu.scanline != scanline || ppu.dot != dot {
+		ppu.step()
+	}
+	ppu.step()
+}
+
+func Test_Tick_SetVBlank(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.stepUntil(vblankScanline, 0)
+	assert.False(t, ppu.status.vblank())
+
+	ppu.step()
+	assert.True(t, ppu.status.vblank())
+}
+
+func Test_Tick_ClearFlagsOnPreRenderScanline(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.status = status(0b1110_0000)
+	ppu.scanline = preRenderScanline
+	ppu.stepUntil(preRenderScanline, 0)
+	assert.Equal(t, status(0b1110_0000), ppu.status)
+
+	ppu.step()
+	assert.Equal(t, status(0b0000_0000), ppu.status)
+}
+
+func Test_Tick_CPUCycleIs3Dots(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.Tick(114 * 3)
+
+	assert.Equal(t, 1, ppu.scanline)
+	assert.Equal(t, 1, ppu.dot)
+}
+
+func Test_Tick_SkipDotOnOddFrame(t *testing.T) {
+	tests := []struct {
+		name  string
+		mask  mask
+		frame uint64
+		want  int
+	}{
+		{name: "EvenFrame", mask: mask(0b0000_1000), frame: 0, want: DotsPerScanline * ScanlinesPerFrame},
+		{name: "OddFrame", mask: mask(0b0000_1000), frame: 1, want: DotsPerScanline*ScanlinesPerFrame - 1},
+		{name: "OddFrameWithoutRendering", mask: mask(0b0000_0000), frame: 1, want: DotsPerScanline * ScanlinesPerFrame},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ppu := newPPUForTest([]byte{}, rom.Horizontal)
+			ppu.mask = tt.mask
+			ppu.frameCount = tt.frame
+
+			dots := 0
+			for ppu.frameCount == tt.frame {
+				ppu.step()
+				dots++
+			}
+
+			assert.Equal(t, tt.want, dots)
+		})
+	}
+}
+
+func Test_Tick_SpriteZeroHit(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0001_1110)
+	// 背景 (0, 0) と、その 1 ライン下から表示されるスプライト 0 は全面不透明
+	ppu.writeVRAM(0x20_00+1, 0x02)
+	copy(ppu.oam[:], []byte{4, 0x02, 0b0000_0000, 10})
+
+	// (x=10, y=5) はドット 11 で描画される
+	ppu.stepUntil(5, 10)
+	assert.Equal(t, status(0), ppu.status&0b0100_0000)
+
+	ppu.step()
+	assert.Equal(t, status(0b0100_0000), ppu.status&0b0100_0000)
+}
+
+func Test_Tick_NoSpriteZeroHitOnTransparentBackground(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0001_1110)
+	copy(ppu.oam[:], []byte{4, 0x02, 0b0000_0000, 10})
+	ppu.stepUntil(Height-1, Width)
+
+	assert.Equal(t, status(0), ppu.status&0b0100_0000)
+}
+
+func Test_Tick_SpriteOverflow(t *testing.T) {
+	tests := []struct {
+		name    string
+		sprites int
+		want    status
+	}{
+		{name: "8Sprites", sprites: 8, want: status(0)},
+		{name: "9Sprites", sprites: 9, want: status(0b0010_0000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ppu := newPPUForRenderTest()
+			ppu.mask = mask(0b0001_0000)
+			for i := range tt.sprites {
+				ppu.oam[i*4] = 100
+			}
+
+			ppu.stepUntil(100, Width-1)
+			assert.Equal(t, status(0), ppu.status&0b0010_0000)
+
+			ppu.step()
+			assert.Equal(t, tt.want, ppu.status&0b0010_0000)
+		})
+	}
+}
+
+func Test_Tick_SpriteOverflowHardwareBug(t *testing.T) {
+	ppu := newPPUForRenderTest()
+	ppu.mask = mask(0b0001_0000)
+	for i := range 8 {
+		ppu.oam[i*4] = 100
+	}
+	// 9 個目は Y 座標が比較され、範囲外と判定される
+	ppu.oam[8*4] = 200
+	// 10 個目は Y 座標ではなくタイル番号が比較されるため、
+	// Y 座標が範囲外でもオーバーフローになる
+	ppu.oam[9*4] = 200
+	ppu.oam[9*4+1] = 100
+	ppu.stepUntil(100, Width)
+
+	assert.Equal(t, status(0b0010_0000), ppu.status&0b0010_0000)
+}
+
+func Test_FrameCount_IncrementOnVBlank(t *testing.T) {
+	ppu := newPPUForTest([]byte{}, rom.Horizontal)
+	ppu.stepUntil(vblankScanline, 0)
+	assert.Equal(t, uint64(0), ppu.FrameCount())
+
+	ppu.step()
+	assert.Equal(t, uint64(1), ppu.FrameCount())
+
+	// 次のフレームに入っても数は変わらない
+	ppu.stepUntil(0, 0)
+	assert.Equal(t, uint64(1), ppu.FrameCount())
+}
+
+// a12Cartridge はパターンテーブルへのアクセスでアドレスの A12 が立ち上がった回数を数える。
+type a12Cartridge struct {
+	a12    bool
+	rising int
+}
+
+func (c *a12Cartridge) ReadCHR(address uint16) byte {
+	a12 := address&0x10_00 != 0
+	if a12 && !c.a12 {
+		c.rising++
+	}
+	c.a12 = a12
+
+	return 0
+}
+
+func (c *a12Cartridge) WriteCHR(address uint16, data byte) {}
+
+func Test_Tick_SpriteFetchRaisesA12OncePerScanline(t *testing.T) {
+	tests := []struct {
+		name string
+		mask mask
+		want int
+	}{
+		// スプライトがなくても空読みするため、プリレンダーラインと 240 本の可視ラインで 1 回ずつ立ち上がる
+		{name: "Rendering", mask: mask(0b0000_1000), want: 241},
+		{name: "Disabled", mask: mask(0b0000_0000), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cartridge := &a12Cartridge{}
+			ppu := NewPPU(cartridge, rom.Horizontal)
+			// 背景は 0x0000、スプライトは 0x1000 のパターンテーブルを使う
+			ppu.ctrl = control(0b0000_1000)
+			ppu.mask = tt.mask
+			ppu.renderForTest()
+
+			assert.Equal(t, tt.want, cartridge.rising)
+		})
+	}
+}
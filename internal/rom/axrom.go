@@ -0,0 +1,80 @@
+package rom
+
+import "io"
+
+// axrom はマッパー 7。PRG ROM を 32KB 単位で切り替え、1 画面ミラーリングで使う VRAM を選ぶ。CHR は 8KB の RAM。
+// 0x8000-0xFFFF への書き込みがレジスタになり、バスコンフリクトが起きる。
+//
+//	---MPPP  M: 1 画面ミラーリングで使う VRAM、P: 32KB の PRG バンク
+//
+// https://www.nesdev.org/wiki/AxROM
+type axrom struct {
+	prg    []byte
+	chr    []byte
+	chrRAM bool
+	bank   byte
+}
+
+func newAxROM(rom *ROM) Mapper {
+	chr, chrRAM := chrMemory(rom)
+
+	return &axrom{
+		prg:    rom.Prg,
+		chr:    chr,
+		chrRAM: chrRAM,
+	}
+}
+
+func (m *axrom) ReadPRG(address uint16) byte {
+	if address < 0x80_00 || len(m.prg) == 0 {
+		return 0
+	}
+
+	bank := int(m.bank & 0b0111)
+
+	return m.prg[wrapOffset(bank*2*PrgROMPageSize+int(address-0x80_00), len(m.prg))]
+}
+
+func (m *axrom) WritePRG(address uint16, data byte) {
+	if address >= 0x80_00 {
+		m.bank = busConflict(m.ReadPRG(address), data)
+	}
+}
+
+func (m *axrom) ReadCHR(address uint16) byte {
+	return m.chr[wrapOffset(int(address), len(m.chr))]
+}
+
+func (m *axrom) WriteCHR(address uint16, data byte) {
+	if m.chrRAM {
+		m.chr[wrapOffset(int(address), len(m.chr))] = data
+	}
+}
+
+func (m *axrom) Mirroring() Mirroring {
+	if m.bank&0b1_0000 != 0 {
+		return SingleScreenB
+	}
+
+	return SingleScreenA
+}
+
+func (m *axrom) IRQ() bool {
+	return false
+}
+
+func (m *axrom) SaveState(w io.Writer) error {
+	if m.chrRAM {
+		return saveState(w, m.bank, m.chr)
+	}
+
+	return saveState(w, m.bank)
+}
+
+func (m *axrom) LoadState(r io.Reader) error {
+	if m.chrRAM {
+		return loadState(r, &m.bank, m.chr)
+	}
+
+	return loadState(r, &m.bank)
+}
@@ -0,0 +1,53 @@
+package rom
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AxROM(t *testing.T) {
+	tests := []struct {
+		name          string
+		rom           byte
+		data          byte
+		wantLow       byte
+		wantMirroring Mirroring
+	}{
+		// inesForTest は 16KB ごとにページ番号を書き込むため、32KB のバンク n の先頭は 2n になる
+		{name: "Bank2", rom: 0xFF, data: 0b0_0010, wantLow: 4, wantMirroring: SingleScreenA},
+		{name: "Bank3/SingleScreenB", rom: 0xFF, data: 0b1_0011, wantLow: 6, wantMirroring: SingleScreenB},
+		// 書き込んだ値と ROM の値の AND がレジスタの値になる
+		{name: "BusConflict", rom: 0b0_0001, data: 0b1_0011, wantLow: 2, wantMirroring: SingleScreenA},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := inesForTest(7, 8, 0)
+			setPRGForTest(raw, 0x10, tt.rom)
+			mapper := newMapperForTest(t, raw)
+			mapper.WritePRG(0x80_10, tt.data)
+
+			assert.Equal(t, tt.wantLow, mapper.ReadPRG(0x80_00))
+			assert.Equal(t, tt.wantLow+1, mapper.ReadPRG(0xC0_00))
+			assert.Equal(t, tt.wantMirroring, mapper.Mirroring())
+		})
+	}
+}
+
+func Test_AxROM_State(t *testing.T) {
+	raw := inesForTest(7, 8, 0)
+	setPRGForTest(raw, 0x10, 0xFF)
+	mapper := newMapperForTest(t, raw)
+	mapper.WritePRG(0x80_10, 0b1_0001)
+	mapper.WriteCHR(0x00_00, 0x34)
+
+	var state bytes.Buffer
+	assert.NoError(t, mapper.SaveState(&state))
+
+	restored := newMapperForTest(t, raw)
+	assert.NoError(t, restored.LoadState(&state))
+	assert.Equal(t, byte(2), restored.ReadPRG(0x80_00))
+	assert.Equal(t, SingleScreenB, restored.Mirroring())
+	assert.Equal(t, byte(0x34), restored.ReadCHR(0x00_00))
+}
@@ -0,0 +1,79 @@
+package rom
+
+import "io"
+
+// cnrom はマッパー 3。PRG ROM は NROM と同じく 16KB か 32KB で、CHR ROM を 8KB 単位で切り替える。
+// 0x8000-0xFFFF への書き込みがバンク番号になり、バスコンフリクトが起きる。
+//
+// https://www.nesdev.org/wiki/CNROM
+type cnrom struct {
+	prg       []byte
+	chr       []byte
+	chrRAM    bool
+	mirroring Mirroring
+	bank      byte
+}
+
+func newCNROM(rom *ROM) Mapper {
+	chr, chrRAM := chrMemory(rom)
+
+	return &cnrom{
+		prg:       rom.Prg,
+		chr:       chr,
+		chrRAM:    chrRAM,
+		mirroring: rom.ScreenMirroring,
+	}
+}
+
+func (m *cnrom) ReadPRG(address uint16) byte {
+	if address < 0x80_00 || len(m.prg) == 0 {
+		return 0
+	}
+
+	return m.prg[wrapOffset(int(address-0x80_00), len(m.prg))]
+}
+
+func (m *cnrom) WritePRG(address uint16, data byte) {
+	if address >= 0x80_00 {
+		m.bank = busConflict(m.ReadPRG(address), data)
+	}
+}
+
+func (m *cnrom) ReadCHR(address uint16) byte {
+	return m.chr[m.chrOffset(address)]
+}
+
+func (m *cnrom) WriteCHR(address uint16, data byte) {
+	if m.chrRAM {
+		m.chr[m.chrOffset(address)] = data
+	}
+}
+
+// chrOffset は 0x0000-0x1FFF のアドレスを CHR ROM 上の位置に変換する。
+func (m *cnrom) chrOffset(address uint16) int {
+	return wrapOffset(int(m.bank)*ChrROMPageSize+int(address), len(m.chr))
+}
+
+func (m *cnrom) Mirroring() Mirroring {
+	return m.mirroring
+}
+
+func (m *cnrom) IRQ() bool {
+	return false
+}
+
+func (m *cnrom) SaveState(w io.Writer) error {
+	if m.chrRAM {
+		return saveState(w, m.bank, m.chr)
+	}
+
+	return saveState(w, m.bank)
+}
+
+func (m *cnrom) LoadState(r io.Reader) error {
+	if m.chrRAM {
+		return loadState(r, &m.bank, m.chr)
+	}
+
+	return loadState(r, &m.bank)
+}
@@ -0,0 +1,49 @@
+package rom
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CNROM_CHR(t *testing.T) {
+	tests := []struct {
+		name string
+		rom  byte
+		data byte
+		want byte
+	}{
+		// inesForTest は 1KB ごとにバンク番号を書き込むため、8KB のバンク n の先頭は 8n になる
+		{name: "Switch", rom: 0xFF, data: 2, want: 16},
+		{name: "Wrap", rom: 0xFF, data: 5, want: 8},
+		// 書き込んだ値と ROM の値の AND がバンク番号になる
+		{name: "BusConflict", rom: 0b0001, data: 0b0011, want: 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := inesForTest(3, 1, 4)
+			setPRGForTest(raw, 0x10, tt.rom)
+			mapper := newMapperForTest(t, raw)
+			// NROM-128 と同じく 0xC000 には 0x8000 のミラーが見える
+			mapper.WritePRG(0xC0_10, tt.data)
+
+			assert.Equal(t, tt.want, mapper.ReadCHR(0x00_00))
+			assert.Equal(t, tt.want+4, mapper.ReadCHR(0x10_00))
+		})
+	}
+}
+
+func Test_CNROM_State(t *testing.T) {
+	raw := inesForTest(3, 2, 4)
+	setPRGForTest(raw, 0x10, 0xFF)
+	mapper := newMapperForTest(t, raw)
+	mapper.WritePRG(0x80_10, 3)
+
+	var state bytes.Buffer
+	assert.NoError(t, mapper.SaveState(&state))
+
+	restored := newMapperForTest(t, raw)
+	assert.NoError(t, restored.LoadState(&state))
+	assert.Equal(t, byte(24), restored.ReadCHR(0x00_00))
+}
@@ -0,0 +1,128 @@
+package rom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Mapper はカートリッジのマッパー。CPU の 0x4020-0xFFFF と PPU のパターンテーブル (0x0000-0x1FFF) へのアクセスを受け持つ。
+//
+// https://www.nesdev.org/wiki/Mapper
+type Mapper interface {
+	// ReadPRG は CPU の 0x4020-0xFFFF から読み込む。
+	ReadPRG(address uint16) byte
+	// WritePRG は CPU の 0x4020-0xFFFF に書き込む。マッパーのレジスタへの書き込みもここに来る。
+	WritePRG(address uint16, data byte)
+	// ReadCHR は PPU の 0x0000-0x1FFF から読み込む。
+	ReadCHR(address uint16) byte
+	// WriteCHR は PPU の 0x0000-0x1FFF に書き込む。CHR ROM への書き込みは無視する。
+	WriteCHR(address uint16, data byte)
+	// Mirroring は今のネームテーブルのミラーリングを返す。
+	Mirroring() Mirroring
+	// IRQ はマッパーが IRQ 線をアサートしているかどうかを返す。
+	IRQ() bool
+	// SaveState はレジスタと RAM の内容を w に書き出す。
+	SaveState(w io.Writer) error
+	// LoadState は SaveState で書き出した内容を r から読み込む。
+	LoadState(r io.Reader) error
+}
+
+// Clocked は CPU のサイクルを数える必要のあるマッパー。バスは CPU が進むたびに Tick を呼び出す。
+type Clocked interface {
+	// Tick は CPU が cycles サイクル進んだことを知らせる。
+	Tick(cycles uint16)
+}
+
+// mappers はマッパー番号ごとのマッパーの作り方。
+var mappers = map[uint16]func(rom *ROM) Mapper{
+	0: newNROM,
+	1: newMMC1,
+	2: newUxROM,
+	3: newCNROM,
+	4: newMMC3,
+	7: newAxROM,
+}
+
+// NewMapper は rom のヘッダにあるマッパー番号のマッパーを作る。
+func NewMapper(rom *ROM) (Mapper, error) {
+	newMapper, ok := mappers[rom.Mapper]
+	if !ok {
+		return nil, unsupportedMapperError(rom.Mapper)
+	}
+
+	return newMapper(rom), nil
+}
+
+func unsupportedMapperError(mapper uint16) error {
+	return fmt.Errorf("unsupported mapper %d", mapper)
+}
+
+const (
+	// PrgRAMSize はヘッダに大きさのないカートリッジの 0x6000-0x7FFF に置かれる PRG RAM の大きさ。
+	PrgRAMSize = 8_192
+	// ChrRAMSize は CHR ROM を持たないカートリッジの CHR RAM の大きさ。
+	ChrRAMSize = 8_192
+)
+
+// prgRAMMemory は rom の 0x6000-0x7FFF に置く PRG RAM を確保する。
+// PRG RAM の大きさは NES 2.0 のヘッダにあればそれに従い、なければ 8KB にする。
+// 8KB より小さい PRG RAM は 0x6000-0x7FFF に繰り返し見える。
+func prgRAMMemory(rom *ROM) []byte {
+	if size := rom.PrgRAMSize + rom.PrgNVRAMSize; size > 0 {
+		return make([]byte, size)
+	}
+
+	return make([]byte, PrgRAMSize)
+}
+
+// chrMemory は rom の CHR ROM を返す。CHR ROM がなければ代わりに CHR RAM を確保し、true を返す。
+// CHR RAM の大きさは NES 2.0 のヘッダにあればそれに従い、8KB より小さくはしない。
+func chrMemory(rom *ROM) ([]byte, bool) {
+	if len(rom.Chr) == 0 {
+		return make([]byte, max(rom.ChrRAMSize+rom.ChrNVRAMSize, ChrRAMSize)), true
+	}
+
+	return rom.Chr, false
+}
+
+// busConflict は CPU がバンクレジスタに書き込んだときにレジスタに届く値を返す。
+// ディスクリートロジックのボードは書き込み中も PRG ROM が出力を止めないため、rom と data がぶつかって 0 が勝つ。
+//
+// https://www.nesdev.org/wiki/Bus_conflict
+func busConflict(rom byte, data byte) byte {
+	return rom & data
+}
+
+// wrapOffset は offset を大きさ size のメモリの中に折り返す。offset が負でも 0 以上 size 未満を返す。
+// ROM がバンクや CPU のウィンドウより小さいときは、実機と同じく ROM 全体が繰り返し見える。
+func wrapOffset(offset int, size int) int {
+	offset %= size
+	if offset < 0 {
+		offset += size
+	}
+
+	return offset
+}
+
+// saveState は values を順に w に書き出す。
+func saveState(w io.Writer, values ...any) error {
+	for _, value := range values {
+		if err := binary.Write(w, binary.LittleEndian, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadState は saveState で書き出した values を同じ順に r から読み込む。
+func loadState(r io.Reader, values ...any) error {
+	for _, value := range values {
+		if err := binary.Read(r, binary.LittleEndian, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package rom
+
+import "io"
+
+// mmc1 はマッパー 1。0x8000-0xFFFF への 1 ビットずつの書き込みを 5 ビットのシフトレジスタに集め、
+// 5 回目の書き込みでアドレスの bit 13-14 が選ぶレジスタに書き込む。
+//
+//	0x8000-0x9FFF control   ---CPPMM  C: CHR バンクモード、P: PRG バンクモード、M: ミラーリング
+//	0xA000-0xBFFF CHR bank 0 ---CCCCC  0x0000 の 4KB (8KB モードでは bit 0 を無視した 8KB)
+//	0xC000-0xDFFF CHR bank 1 ---CCCCC  0x1000 の 4KB (8KB モードでは使わない)
+//	0xE000-0xFFFF PRG bank   ---RPPPP  R: PRG RAM を無効にする、P: 16KB の PRG バンク
+//
+// https://www.nesdev.org/wiki/MMC1
+type mmc1 struct {
+	prg    []byte
+	prgRAM []byte
+	chr    []byte
+	chrRAM bool
+
+	shift    byte
+	control  byte
+	chrBank0 byte
+	chrBank1 byte
+	prgBank  byte
+
+	// cycles は電源投入から経過した CPU のサイクル数。
+	cycles uint64
+	// lastWrite は最後にシフトレジスタに書き込んだときの cycles。written が false なら意味を持たない。
+	lastWrite uint64
+	written   bool
+}
+
+// shiftReset はシフトレジスタの初期値。bit 4 まで 1 が押し出されたら 5 回書き込んだことになる。
+const shiftReset = 0b1_0000
+
+func newMMC1(rom *ROM) Mapper {
+	chr, chrRAM := chrMemory(rom)
+
+	return &mmc1{
+		prg:    rom.Prg,
+		prgRAM: prgRAMMemory(rom),
+		chr:    chr,
+		chrRAM: chrRAM,
+		shift:  shiftReset,
+		// 電源投入時は最後のバンクが 0xC000 に固定されている
+		control: 0b0_11_00,
+	}
+}
+
+func (m *mmc1) Tick(cycles uint16) {
+	m.cycles += uint64(cycles)
+}
+
+func (m *mmc1) ReadPRG(address uint16) byte {
+	switch {
+	case address >= 0x80_00:
+		if len(m.prg) == 0 {
+			return 0
+		}
+
+		return m.prg[wrapOffset(m.prgOffset(address), len(m.prg))]
+	case address >= 0x60_00:
+		if !m.prgRAMEnabled() {
+			return 0
+		}
+
+		return m.prgRAM[wrapOffset(int(address-0x60_00), len(m.prgRAM))]
+	default:
+		return 0
+	}
+}
+
+func (m *mmc1) WritePRG(address uint16, data byte) {
+	switch {
+	case address >= 0x80_00:
+		m.writeShift(address, data)
+	case address >= 0x60_00:
+		if m.prgRAMEnabled() {
+			m.prgRAM[wrapOffset(int(address-0x60_00), len(m.prgRAM))] = data
+		}
+	}
+}
+
+// writeShift はシフトレジスタに 1 ビット書き込む。
+func (m *mmc1) writeShift(address uint16, data byte) {
+	// RMW 命令の 2 回続く書き込みのように、直後のサイクルの書き込みは無視される
+	consecutive := m.written && m.cycles <= m.lastWrite+1
+	m.lastWrite = m.cycles
+	m.written = true
+	if consecutive {
+		return
+	}
+
+	// bit 7 が立っていればシフトレジスタをリセットし、最後のバンクを 0xC000 に固定する
+	if data&0b1000_0000 != 0 {
+		m.shift = shiftReset
+		m.control |= 0b0_11_00
+
+		return
+	}
+
+	full := m.shift&1 == 1
+	m.shift = m.shift>>1 | (data&1)<<4
+	if !full {
+		return
+	}
+
+	value := m.shift
+	m.shift = shiftReset
+
+	switch (address >> 13) & 0b11 {
+	case 0:
+		m.control = value
+	case 1:
+		m.chrBank0 = value
+	case 2:
+		m.chrBank1 = value
+	case 3:
+		m.prgBank = value
+	}
+}
+
+// prgOffset は 0x8000-0xFFFF のアドレスを PRG ROM 上の位置に変換する。
+func (m *mmc1) prgOffset(address uint16) int {
+	bank := int(m.prgBank & 0b0_1111)
+	last := max(len(m.prg)/PrgROMPageSize-1, 0)
+	offset := int(address & 0x3F_FF)
+	low := address < 0xC0_00
+
+	switch (m.control >> 2) & 0b11 {
+	case 0, 1:
+		// 32KB 単位で切り替える。バンク番号の bit 0 は無視する
+		return (bank&^1)*PrgROMPageSize + int(address-0x80_00)
+	case 2:
+		// 0x8000 は最初のバンクに固定し、0xC000 を切り替える
+		if low {
+			return offset
+		}
+
+		return bank*PrgROMPageSize + offset
+	default:
+		// 0x8000 を切り替え、0xC000 は最後のバンクに固定する
+		if low {
+			return bank*PrgROMPageSize + offset
+		}
+
+		return last*PrgROMPageSize + offset
+	}
+}
+
+func (m *mmc1) prgRAMEnabled() bool {
+	return m.prgBank&0b1_0000 == 0
+}
+
+func (m *mmc1) ReadCHR(address uint16) byte {
+	return m.chr[wrapOffset(m.chrOffset(address), len(m.chr))]
+}
+
+func (m *mmc1) WriteCHR(address uint16, data byte) {
+	if m.chrRAM {
+		m.chr[wrapOffset(m.chrOffset(address), len(m.chr))] = data
+	}
+}
+
+// chrOffset は 0x0000-0x1FFF のアドレスを CHR ROM (RAM) 上の位置に変換する。
+func (m *mmc1) chrOffset(address uint16) int {
+	const bankSize = 0x10_00
+
+	if m.control&0b1_00_00 == 0 {
+		// 8KB 単位で切り替える。バンク番号の bit 0 は無視する
+		return int(m.chrBank0&^1)*bankSize + int(address)
+	}
+
+	if address < bankSize {
+		return int(m.chrBank0)*bankSize + int(address)
+	}
+
+	return int(m.chrBank1)*bankSize + int(address-bankSize)
+}
+
+func (m *mmc1) Mirroring() Mirroring {
+	switch m.control & 0b11 {
+	case 0:
+		return SingleScreenA
+	case 1:
+		return SingleScreenB
+	case 2:
+		return Vertical
+	default:
+		return Horizontal
+	}
+}
+
+func (m *mmc1) IRQ() bool {
+	return false
+}
+
+func (m *mmc1) SaveState(w io.Writer) error {
+	values := []any{
+		m.shift, m.control, m.chrBank0, m.chrBank1, m.prgBank,
+		m.cycles, m.lastWrite, m.written,
+		m.prgRAM,
+	}
+	if m.chrRAM {
+		values = append(values, m.chr)
+	}
+
+	return saveState(w, values...)
+}
+
+func (m *mmc1) LoadState(r io.Reader) error {
+	values := []any{
+		&m.shift, &m.control, &m.chrBank0, &m.chrBank1, &m.prgBank,
+		&m.cycles, &m.lastWrite, &m.written,
+		m.prgRAM,
+	}
+	if m.chrRAM {
+		values = append(values, m.chr)
+	}
+
+	return loadState(r, values...)
+}
@@ -0,0 +1,176 @@
+package rom
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeMMC1ForTest は MMC1 のシフトレジスタに value を下位ビットから 5 回に分けて書き込む。
+// 書き込みの間は連続したサイクルにならないように CPU を進める。
+func writeMMC1ForTest(mapper Mapper, address uint16, value byte) {
+	for i := range 5 {
+		mapper.(Clocked).Tick(4)
+		mapper.WritePRG(address, value>>i&1)
+	}
+}
+
+func Test_MMC1_PRG(t *testing.T) {
+	tests := []struct {
+		name     string
+		control  byte
+		prgBank  byte
+		wantLow  byte
+		wantHigh byte
+	}{
+		{name: "PowerOn", control: 0b0_11_00, prgBank: 0, wantLow: 0, wantHigh: 7},
+		{name: "32KB", control: 0b0_00_00, prgBank: 2, wantLow: 2, wantHigh: 3},
+		{name: "32KB/IgnoresLowBit", control: 0b0_01_00, prgBank: 5, wantLow: 4, wantHigh: 5},
+		{name: "FixFirst", control: 0b0_10_00, prgBank: 5, wantLow: 0, wantHigh: 5},
+		{name: "FixLast", control: 0b0_11_00, prgBank: 5, wantLow: 5, wantHigh: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapper := newMapperForTest(t, inesForTest(1, 8, 1))
+			writeMMC1ForTest(mapper, 0x80_00, tt.control)
+			writeMMC1ForTest(mapper, 0xE0_00, tt.prgBank)
+
+			assert.Equal(t, tt.wantLow, mapper.ReadPRG(0x80_00))
+			assert.Equal(t, tt.wantHigh, mapper.ReadPRG(0xC0_00))
+		})
+	}
+}
+
+func Test_MMC1_CHR(t *testing.T) {
+	tests := []struct {
+		name     string
+		control  byte
+		chrBank0 byte
+		chrBank1 byte
+		wantLow  byte
+		wantHigh byte
+	}{
+		// inesForTest は 1KB ごとにバンク番号を書き込むため、4KB のバンク n の先頭は 4n になる
+		{name: "8KB", control: 0b0_11_00, chrBank0: 2, chrBank1: 7, wantLow: 8, wantHigh: 12},
+		{name: "8KB/IgnoresLowBit", control: 0b0_11_00, chrBank0: 3, chrBank1: 7, wantLow: 8, wantHigh: 12},
+		{name: "4KB", control: 0b1_11_00, chrBank0: 3, chrBank1: 6, wantLow: 12, wantHigh: 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapper := newMapperForTest(t, inesForTest(1, 2, 4))
+			writeMMC1ForTest(mapper, 0x80_00, tt.control)
+			writeMMC1ForTest(mapper, 0xA0_00, tt.chrBank0)
+			writeMMC1ForTest(mapper, 0xC0_00, tt.chrBank1)
+
+			assert.Equal(t, tt.wantLow, mapper.ReadCHR(0x00_00))
+			assert.Equal(t, tt.wantHigh, mapper.ReadCHR(0x10_00))
+		})
+	}
+}
+
+func Test_MMC1_Mirroring(t *testing.T) {
+	tests := []struct {
+		name    string
+		control byte
+		want    Mirroring
+	}{
+		{name: "SingleScreenA", control: 0b0_11_00, want: SingleScreenA},
+		{name: "SingleScreenB", control: 0b0_11_01, want: SingleScreenB},
+		{name: "Vertical", control: 0b0_11_10, want: Vertical},
+		{name: "Horizontal", control: 0b0_11_11, want: Horizontal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapper := newMapperForTest(t, inesForTest(1, 2, 1))
+			// 0x8000-0x9FFF のどこに書き込んでも control になる
+			writeMMC1ForTest(mapper, 0x9F_FF, tt.control)
+
+			assert.Equal(t, tt.want, mapper.Mirroring())
+		})
+	}
+}
+
+func Test_MMC1_ResetOnBit7(t *testing.T) {
+	mapper := newMapperForTest(t, inesForTest(1, 8, 1))
+	writeMMC1ForTest(mapper, 0x80_00, 0b0_00_10)
+
+	// 途中まで書き込んだビットは bit 7 の書き込みで捨てられ、PRG バンクモードは 3 に戻る
+	for range 3 {
+		mapper.(Clocked).Tick(4)
+		mapper.WritePRG(0xE0_00, 1)
+	}
+	mapper.(Clocked).Tick(4)
+	mapper.WritePRG(0xE0_00, 0b1000_0000)
+	writeMMC1ForTest(mapper, 0xE0_00, 2)
+
+	assert.Equal(t, byte(2), mapper.ReadPRG(0x80_00))
+	assert.Equal(t, byte(7), mapper.ReadPRG(0xC0_00))
+	assert.Equal(t, Vertical, mapper.Mirroring())
+}
+
+func Test_MMC1_IgnoresConsecutiveWrites(t *testing.T) {
+	mapper := newMapperForTest(t, inesForTest(1, 8, 1))
+
+	// RMW 命令は同じ値を 2 回続けて書き込むが、MMC1 は 2 回目を無視する
+	for i := range 5 {
+		mapper.(Clocked).Tick(6)
+		mapper.WritePRG(0xE0_00, byte(3>>i&1))
+		mapper.WritePRG(0xE0_00, byte(3>>i&1))
+	}
+
+	assert.Equal(t, byte(3), mapper.ReadPRG(0x80_00))
+}
+
+func Test_MMC1_PRGRAM(t *testing.T) {
+	mapper := newMapperForTest(t, inesForTest(1, 2, 1))
+	mapper.WritePRG(0x60_00, 0x12)
+	assert.Equal(t, byte(0x12), mapper.ReadPRG(0x60_00))
+
+	// PRG bank の bit 4 が立っていると PRG RAM は読み書きできない
+	writeMMC1ForTest(mapper, 0xE0_00, 0b1_0000)
+	mapper.WritePRG(0x60_00, 0x34)
+	assert.Equal(t, byte(0x00), mapper.ReadPRG(0x60_00))
+
+	writeMMC1ForTest(mapper, 0xE0_00, 0b0_0000)
+	assert.Equal(t, byte(0x12), mapper.ReadPRG(0x60_00))
+}
+
+func Test_MMC1_State(t *testing.T) {
+	raw := inesForTest(1, 8, 0)
+	mapper := newMapperForTest(t, raw)
+	writeMMC1ForTest(mapper, 0x80_00, 0b0_10_10)
+	writeMMC1ForTest(mapper, 0xE0_00, 3)
+	mapper.WritePRG(0x60_00, 0x12)
+	mapper.WriteCHR(0x00_00, 0x34)
+
+	var state bytes.Buffer
+	assert.NoError(t, mapper.SaveState(&state))
+
+	restored := newMapperForTest(t, raw)
+	assert.NoError(t, restored.LoadState(&state))
+	assert.Equal(t, byte(3), restored.ReadPRG(0xC0_00))
+	assert.Equal(t, Vertical, restored.Mirroring())
+	assert.Equal(t, byte(0x12), restored.ReadPRG(0x60_00))
+	assert.Equal(t, byte(0x34), restored.ReadCHR(0x00_00))
+}
+
+func Test_MMC1_CHRRAMSizeFromNES2Header(t *testing.T) {
+	raw := inesForTest(1, 2, 0)
+	// NES 2.0 で CHR RAM を 32KB (64 << 9) にする
+	raw[7] |= 0b0000_1000
+	raw[11] = 0x09
+	mapper := newMapperForTest(t, raw)
+	writeMMC1ForTest(mapper, 0x80_00, 0b1_11_00)
+	writeMMC1ForTest(mapper, 0xA0_00, 0)
+	writeMMC1ForTest(mapper, 0xC0_00, 7)
+	mapper.WriteCHR(0x00_00, 0x12)
+	mapper.WriteCHR(0x10_00, 0x34)
+
+	// 8KB で折り返すなら 4KB のバンク 7 はバンク 1 と同じ場所になる
+	writeMMC1ForTest(mapper, 0xC0_00, 1)
+	assert.Equal(t, byte(0x00), mapper.ReadCHR(0x10_00))
+	writeMMC1ForTest(mapper, 0xC0_00, 7)
+	assert.Equal(t, byte(0x34), mapper.ReadCHR(0x10_00))
+	assert.Equal(t, byte(0x12), mapper.ReadCHR(0x00_00))
+}
@@ -0,0 +1,245 @@
+package rom
+
+import "io"
+
+// mmc3 はマッパー 4。PRG ROM を 8KB 単位、CHR を 1KB 単位で切り替え、PPU のアドレスの A12 の立ち上がりでラインを数えて IRQ を出す。
+// レジスタはアドレスの範囲と偶数・奇数で選ぶ。
+//
+//	0x8000 (偶数) bank select     CP---RRR  C: CHR の反転、P: PRG バンクモード、R: 次に書き込むバンクレジスタ
+//	0x8001 (奇数) bank data       R0-R7 に書き込む
+//	0xA000 (偶数) mirroring       -------M  0: 垂直、1: 水平
+//	0xA001 (奇数) PRG RAM protect EW------  E: PRG RAM を有効にする、W: PRG RAM への書き込みを禁止する
+//	0xC000 (偶数) IRQ latch       カウンタに読み込む値
+//	0xC001 (奇数) IRQ reload      次の A12 の立ち上がりでカウンタに latch を読み込む
+//	0xE000 (偶数) IRQ disable     IRQ を無効にし、出ている IRQ を取り下げる
+//	0xE001 (奇数) IRQ enable
+//
+// https://www.nesdev.org/wiki/MMC3
+type mmc3 struct {
+	prg       []byte
+	prgRAM    []byte
+	chr       []byte
+	chrRAM    bool
+	mirroring Mirroring
+
+	bankSelect    byte
+	banks         [8]byte
+	prgRAMProtect byte
+
+	irqLatch   byte
+	irqCounter byte
+	irqReload  bool
+	irqEnabled bool
+	irq        bool
+	// a12 は最後に PPU がパターンテーブルにアクセスしたときのアドレスの A12。
+	a12 bool
+}
+
+const (
+	mmc3PrgBankSize = 0x20_00
+	mmc3ChrBankSize = 0x04_00
+)
+
+func newMMC3(rom *ROM) Mapper {
+	chr, chrRAM := chrMemory(rom)
+
+	return &mmc3{
+		prg:       rom.Prg,
+		prgRAM:    prgRAMMemory(rom),
+		chr:       chr,
+		chrRAM:    chrRAM,
+		mirroring: rom.ScreenMirroring,
+		// protect を書き込まずに PRG RAM を使うゲームがあるため、電源投入時は有効にしておく
+		prgRAMProtect: 0b1000_0000,
+	}
+}
+
+func (m *mmc3) ReadPRG(address uint16) byte {
+	switch {
+	case address >= 0x80_00:
+		if len(m.prg) == 0 {
+			return 0
+		}
+
+		return m.prg[wrapOffset(m.prgOffset(address), len(m.prg))]
+	case address >= 0x60_00:
+		if m.prgRAMProtect&0b1000_0000 == 0 {
+			return 0
+		}
+
+		return m.prgRAM[wrapOffset(int(address-0x60_00), len(m.prgRAM))]
+	default:
+		return 0
+	}
+}
+
+func (m *mmc3) WritePRG(address uint16, data byte) {
+	if address < 0x60_00 {
+		return
+	}
+	if address < 0x80_00 {
+		if m.prgRAMProtect&0b1100_0000 == 0b1000_0000 {
+			m.prgRAM[wrapOffset(int(address-0x60_00), len(m.prgRAM))] = data
+		}
+
+		return
+	}
+
+	odd := address&1 == 1
+	switch address & 0xE0_00 {
+	case 0x80_00:
+		if odd {
+			m.banks[m.bankSelect&0b0111] = data
+		} else {
+			m.bankSelect = data
+		}
+	case 0xA0_00:
+		if odd {
+			m.prgRAMProtect = data
+		} else if m.mirroring != FourScreen {
+			// 4 画面ミラーリングのカートリッジはミラーリングを切り替えられない
+			m.mirroring = Vertical
+			if data&1 == 1 {
+				m.mirroring = Horizontal
+			}
+		}
+	case 0xC0_00:
+		if odd {
+			m.irqCounter = 0
+			m.irqReload = true
+		} else {
+			m.irqLatch = data
+		}
+	case 0xE0_00:
+		m.irqEnabled = odd
+		if !odd {
+			m.irq = false
+		}
+	}
+}
+
+// prgOffset は 0x8000-0xFFFF のアドレスを PRG ROM 上の位置に変換する。
+// 0xE000 は最後のバンクに固定され、PRG バンクモードで R6 と最後から 2 番目のバンクの場所が入れ替わる。
+func (m *mmc3) prgOffset(address uint16) int {
+	last := max(len(m.prg)/mmc3PrgBankSize-1, 0)
+	slot := int(address-0x80_00) / mmc3PrgBankSize
+	if m.bankSelect&0b0100_0000 != 0 && (slot == 0 || slot == 2) {
+		slot = 2 - slot
+	}
+
+	var bank int
+	switch slot {
+	case 0:
+		bank = int(m.banks[6] & 0b0011_1111)
+	case 1:
+		bank = int(m.banks[7] & 0b0011_1111)
+	case 2:
+		// PRG ROM が 8KB しかなければ -1 になるが、ReadPRG の wrapOffset で最後のバンクに折り返す
+		bank = last - 1
+	default:
+		bank = last
+	}
+
+	return bank*mmc3PrgBankSize + int(address&(mmc3PrgBankSize-1))
+}
+
+func (m *mmc3) ReadCHR(address uint16) byte {
+	m.clockA12(address)
+
+	return m.chr[wrapOffset(m.chrOffset(address), len(m.chr))]
+}
+
+func (m *mmc3) WriteCHR(address uint16, data byte) {
+	m.clockA12(address)
+
+	if m.chrRAM {
+		m.chr[wrapOffset(m.chrOffset(address), len(m.chr))] = data
+	}
+}
+
+// chrOffset は 0x0000-0x1FFF のアドレスを CHR ROM (RAM) 上の位置に変換する。
+// R0 と R1 は 2KB、R2-R5 は 1KB のバンクで、CHR の反転が立つと前半と後半の 4KB が入れ替わる。
+func (m *mmc3) chrOffset(address uint16) int {
+	if m.bankSelect&0b1000_0000 != 0 {
+		address ^= 0x10_00
+	}
+
+	var bank int
+	switch slot := address / mmc3ChrBankSize; slot {
+	case 0, 1:
+		bank = int(m.banks[0]&^1) + int(slot)
+	case 2, 3:
+		bank = int(m.banks[1]&^1) + int(slot-2)
+	default:
+		bank = int(m.banks[slot-2])
+	}
+
+	return bank*mmc3ChrBankSize + int(address&(mmc3ChrBankSize-1))
+}
+
+// clockA12 は PPU のアクセスしたアドレスの A12 が立ち上がっていればスキャンラインカウンタを進める。
+// カウンタが 0 か reload が立っていれば latch を読み込み、そうでなければ 1 減らす。その結果が 0 で IRQ が有効なら IRQ を出す。
+//
+// 実機の MMC3 は A12 が低い期間が短い立ち上がりを無視するが、このマッパーにはパターンテーブル以外への
+// アクセスが届かないため、スプライトのフェッチの間に挟まるネームテーブルの読み込みで A12 が下がることはない。
+//
+// https://www.nesdev.org/wiki/MMC3#IRQ_Specifics
+func (m *mmc3) clockA12(address uint16) {
+	a12 := address&0x10_00 != 0
+	rising := a12 && !m.a12
+	m.a12 = a12
+	if !rising {
+		return
+	}
+
+	if m.irqCounter == 0 || m.irqReload {
+		m.irqCounter = m.irqLatch
+		m.irqReload = false
+	} else {
+		m.irqCounter--
+	}
+
+	if m.irqCounter == 0 && m.irqEnabled {
+		m.irq = true
+	}
+}
+
+func (m *mmc3) Mirroring() Mirroring {
+	return m.mirroring
+}
+
+func (m *mmc3) IRQ() bool {
+	return m.irq
+}
+
+func (m *mmc3) SaveState(w io.Writer) error {
+	values := []any{
+		byte(m.mirroring), m.bankSelect, m.banks, m.prgRAMProtect,
+		m.irqLatch, m.irqCounter, m.irqReload, m.irqEnabled, m.irq, m.a12,
+		m.prgRAM,
+	}
+	if m.chrRAM {
+		values = append(values, m.chr)
+	}
+
+	return saveState(w, values...)
+}
+
+func (m *mmc3) LoadState(r io.Reader) error {
+	var mirroring byte
+	values := []any{
+		&mirroring, &m.bankSelect, &m.banks, &m.prgRAMProtect,
+		&m.irqLatch, &m.irqCounter, &m.irqReload, &m.irqEnabled, &m.irq, &m.a12,
+		m.prgRAM,
+	}
+	if m.chrRAM {
+		values = append(values, m.chr)
+	}
+
+	if err := loadState(r, values...); err != nil {
+		return err
+	}
+	m.mirroring = Mirroring(mirroring)
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package rom
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// clockMMC3ForTest は PPU が背景 (0x0000) とスプライト (0x1000) のパターンを読むのを真似て、A12 を 1 回立ち上げる。
+func clockMMC3ForTest(mapper Mapper) {
+	mapper.ReadCHR(0x00_00)
+	mapper.ReadCHR(0x10_00)
+}
+
+func Test_MMC3_PRG(t *testing.T) {
+	tests := []struct {
+		name       string
+		bankSelect byte
+		want       [4]byte
+	}{
+		{name: "Mode0", bankSelect: 0b0000_0000, want: [4]byte{3, 5, 14, 15}},
+		{name: "Mode1", bankSelect: 0b0100_0000, want: [4]byte{14, 5, 3, 15}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := inesForTest(4, 8, 1)
+			// 8KB のバンクごとに 2 バイト目にバンク番号を書き込む
+			for bank := range 16 {
+				setPRGForTest(raw, bank*0x20_00+1, byte(bank))
+			}
+			mapper := newMapperForTest(t, raw)
+			mapper.WritePRG(0x80_00, 6)
+			mapper.WritePRG(0x80_01, 3)
+			mapper.WritePRG(0x80_00, 7)
+			mapper.WritePRG(0x80_01, 5)
+			mapper.WritePRG(0x80_00, tt.bankSelect|7)
+
+			got := [4]byte{
+				mapper.ReadPRG(0x80_01),
+				mapper.ReadPRG(0xA0_01),
+				mapper.ReadPRG(0xC0_01),
+				mapper.ReadPRG(0xE0_01),
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_MMC3_CHR(t *testing.T) {
+	tests := []struct {
+		name       string
+		bankSelect byte
+		want       [8]byte
+	}{
+		// R0 と R1 は bit 0 を無視した 2KB のバンク
+		{name: "Normal", bankSelect: 0b0000_0000, want: [8]byte{10, 11, 20, 21, 30, 31, 32, 33}},
+		{name: "Inversion", bankSelect: 0b1000_0000, want: [8]byte{30, 31, 32, 33, 10, 11, 20, 21}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapper := newMapperForTest(t, inesForTest(4, 2, 8))
+			for register, bank := range []byte{10, 21, 30, 31, 32, 33} {
+				mapper.WritePRG(0x80_00, byte(register))
+				mapper.WritePRG(0x80_01, bank)
+			}
+			mapper.WritePRG(0x80_00, tt.bankSelect)
+
+			var got [8]byte
+			for i := range got {
+				got[i] = mapper.ReadCHR(uint16(i) * 0x04_00)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_MMC3_Mirroring(t *testing.T) {
+	tests := []struct {
+		name       string
+		fourScreen bool
+		data       byte
+		want       Mirroring
+	}{
+		{name: "Vertical", data: 0, want: Vertical},
+		{name: "Horizontal", data: 1, want: Horizontal},
+		{name: "FourScreen", fourScreen: true, data: 1, want: FourScreen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := inesForTest(4, 2, 1)
+			if tt.fourScreen {
+				raw[6] |= 0b0000_1000
+			}
+			mapper := newMapperForTest(t, raw)
+			mapper.WritePRG(0xA0_00, tt.data)
+
+			assert.Equal(t, tt.want, mapper.Mirroring())
+		})
+	}
+}
+
+func Test_MMC3_PRGRAM(t *testing.T) {
+	tests := []struct {
+		name    string
+		protect byte
+		want    byte
+	}{
+		{name: "Enabled", protect: 0b1000_0000, want: 0x12},
+		{name: "WriteProtected", protect: 0b1100_0000, want: 0x00},
+		{name: "Disabled", protect: 0b0000_0000, want: 0x00},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapper := newMapperForTest(t, inesForTest(4, 2, 1))
+			mapper.WritePRG(0xA0_01, tt.protect)
+			mapper.WritePRG(0x60_00, 0x12)
+
+			assert.Equal(t, tt.want, mapper.ReadPRG(0x60_00))
+		})
+	}
+}
+
+func Test_MMC3_IRQ(t *testing.T) {
+	mapper := newMapperForTest(t, inesForTest(4, 2, 1))
+	mapper.WritePRG(0xC0_00, 3)
+	mapper.WritePRG(0xC0_01, 0)
+	mapper.WritePRG(0xE0_01, 0)
+
+	// 最初の立ち上がりで latch を読み込み、そこから 3 ラインで 0 になる
+	for range 3 {
+		clockMMC3ForTest(mapper)
+		assert.False(t, mapper.IRQ())
+	}
+	clockMMC3ForTest(mapper)
+	assert.True(t, mapper.IRQ())
+
+	// A12 が立ったままのアクセスは立ち上がりではない
+	mapper.WritePRG(0xE0_00, 0)
+	mapper.WritePRG(0xE0_01, 0)
+	mapper.ReadCHR(0x10_10)
+	assert.False(t, mapper.IRQ())
+
+	// 0 になったカウンタは latch を読み込み直す
+	for range 3 {
+		clockMMC3ForTest(mapper)
+	}
+	assert.False(t, mapper.IRQ())
+	clockMMC3ForTest(mapper)
+	assert.True(t, mapper.IRQ())
+
+	// 0xE000 は IRQ を取り下げ、無効にする
+	mapper.WritePRG(0xE0_00, 0)
+	assert.False(t, mapper.IRQ())
+	for range 8 {
+		clockMMC3ForTest(mapper)
+	}
+	assert.False(t, mapper.IRQ())
+}
+
+func Test_MMC3_State(t *testing.T) {
+	raw := inesForTest(4, 2, 0)
+	mapper := newMapperForTest(t, raw)
+	mapper.WritePRG(0xA0_00, 1)
+	mapper.WritePRG(0xC0_00, 5)
+	mapper.WritePRG(0xC0_01, 0)
+	clockMMC3ForTest(mapper)
+	mapper.WritePRG(0x60_00, 0x12)
+	mapper.WriteCHR(0x00_00, 0x34)
+
+	var state bytes.Buffer
+	assert.NoError(t, mapper.SaveState(&state))
+
+	restored := newMapperForTest(t, raw)
+	assert.NoError(t, restored.LoadState(&state))
+	assert.Equal(t, Horizontal, restored.Mirroring())
+	assert.Equal(t, byte(0x12), restored.ReadPRG(0x60_00))
+	assert.Equal(t, byte(0x34), restored.ReadCHR(0x00_00))
+
+	// カウンタは 5 から数え続ける
+	restored.WritePRG(0xE0_01, 0)
+	for range 4 {
+		clockMMC3ForTest(restored)
+	}
+	assert.False(t, restored.IRQ())
+	clockMMC3ForTest(restored)
+	assert.True(t, restored.IRQ())
+}
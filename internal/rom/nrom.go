@@ -0,0 +1,82 @@
+package rom
+
+import "io"
+
+// nrom はバンク切り替えを持たないマッパー 0。
+// PRG ROM は 16KB (NROM-128、0xC000 にミラーされる) か 32KB (NROM-256) で、CHR は 8KB。
+//
+// https://www.nesdev.org/wiki/NROM
+type nrom struct {
+	prg       []byte
+	prgRAM    []byte
+	chr       []byte
+	chrRAM    bool
+	mirroring Mirroring
+}
+
+func newNROM(rom *ROM) Mapper {
+	chr, chrRAM := chrMemory(rom)
+
+	return &nrom{
+		prg:       rom.Prg,
+		prgRAM:    prgRAMMemory(rom),
+		chr:       chr,
+		chrRAM:    chrRAM,
+		mirroring: rom.ScreenMirroring,
+	}
+}
+
+func (m *nrom) ReadPRG(address uint16) byte {
+	switch {
+	case address >= 0x80_00:
+		if len(m.prg) == 0 {
+			return 0
+		}
+
+		return m.prg[wrapOffset(int(address-0x80_00), len(m.prg))]
+	case address >= 0x60_00:
+		return m.prgRAM[wrapOffset(int(address-0x60_00), len(m.prgRAM))]
+	default:
+		return 0
+	}
+}
+
+func (m *nrom) WritePRG(address uint16, data byte) {
+	if 0x60_00 <= address && address < 0x80_00 {
+		m.prgRAM[wrapOffset(int(address-0x60_00), len(m.prgRAM))] = data
+	}
+}
+
+func (m *nrom) ReadCHR(address uint16) byte {
+	return m.chr[wrapOffset(int(address), len(m.chr))]
+}
+
+func (m *nrom) WriteCHR(address uint16, data byte) {
+	if m.chrRAM {
+		m.chr[wrapOffset(int(address), len(m.chr))] = data
+	}
+}
+
+func (m *nrom) Mirroring() Mirroring {
+	return m.mirroring
+}
+
+func (m *nrom) IRQ() bool {
+	return false
+}
+
+func (m *nrom) SaveState(w io.Writer) error {
+	if m.chrRAM {
+		return saveState(w, m.prgRAM, m.chr)
+	}
+
+	return saveState(w, m.prgRAM)
+}
+
+func (m *nrom) LoadState(r io.Reader) error {
+	if m.chrRAM {
+		return loadState(r, m.prgRAM, m.chr)
+	}
+
+	return loadState(r, m.prgRAM)
+}
@@ -0,0 +1,143 @@
+package rom
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// inesForTest は prgPages 個の 16KB の PRG ROM と chrPages 個の 8KB の CHR ROM を持つマッパー mapper の iNES イメージを作る。
+// 各 PRG ROM のページの先頭にはページ番号を、CHR ROM の 1KB ごとの先頭には 1KB 単位のバンク番号を書き込む。
+func inesForTest(mapper byte, prgPages int, chrPages int) []byte {
+	raw := []byte{
+		'N', 'E', 'S', 0x1A,
+		byte(prgPages), byte(chrPages),
+		mapper << 4, mapper & 0b1111_0000,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	prg := make([]byte, prgPages*PrgROMPageSize)
+	for page := range prgPages {
+		prg[page*PrgROMPageSize] = byte(page)
+	}
+	chr := make([]byte, chrPages*ChrROMPageSize)
+	for bank := range len(chr) / 0x4_00 {
+		chr[bank*0x4_00] = byte(bank)
+	}
+
+	return append(append(raw, prg...), chr...)
+}
+
+// setPRGForTest は iNES イメージ raw の PRG ROM の offset に data を書き込む。
+func setPRGForTest(raw []byte, offset int, data byte) {
+	raw[HeaderSize+offset] = data
+}
+
+func newMapperForTest(t *testing.T, raw []byte) Mapper {
+	t.Helper()
+
+	rom, err := NewROM(raw)
+	assert.NoError(t, err)
+	mapper, err := NewMapper(rom)
+	assert.NoError(t, err)
+
+	return mapper
+}
+
+func Test_NewROM_UnsupportedMapper(t *testing.T) {
+	_, err := NewROM(inesForTest(150, 1, 1))
+
+	assert.EqualError(t, err, "unsupported mapper 150")
+}
+
+func Test_NROM_PRG(t *testing.T) {
+	tests := []struct {
+		name     string
+		prgPages int
+		want     byte
+	}{
+		// NROM-128 は 0xC000 に 0x8000 のミラーが見える
+		{name: "NROM-128", prgPages: 1, want: 0},
+		{name: "NROM-256", prgPages: 2, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapper := newMapperForTest(t, inesForTest(0, tt.prgPages, 1))
+
+			assert.Equal(t, byte(0), mapper.ReadPRG(0x80_00))
+			assert.Equal(t, tt.want, mapper.ReadPRG(0xC0_00))
+		})
+	}
+}
+
+func Test_NROM_PRGRAM(t *testing.T) {
+	mapper := newMapperForTest(t, inesForTest(0, 1, 1))
+	mapper.WritePRG(0x60_00, 0x12)
+	mapper.WritePRG(0x7F_FF, 0x34)
+	// PRG ROM への書き込みは無視する
+	mapper.WritePRG(0x80_00, 0x56)
+
+	assert.Equal(t, byte(0x12), mapper.ReadPRG(0x60_00))
+	assert.Equal(t, byte(0x34), mapper.ReadPRG(0x7F_FF))
+	assert.Equal(t, byte(0x00), mapper.ReadPRG(0x80_00))
+}
+
+func Test_NROM_CHR(t *testing.T) {
+	tests := []struct {
+		name     string
+		chrPages int
+		want     byte
+	}{
+		{name: "CHRROM", chrPages: 1, want: 0x00},
+		{name: "CHRRAM", chrPages: 0, want: 0x55},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapper := newMapperForTest(t, inesForTest(0, 1, tt.chrPages))
+			mapper.WriteCHR(0x1F_FF, 0x55)
+
+			assert.Equal(t, tt.want, mapper.ReadCHR(0x1F_FF))
+		})
+	}
+}
+
+func Test_NROM_State(t *testing.T) {
+	mapper := newMapperForTest(t, inesForTest(0, 1, 0))
+	mapper.WritePRG(0x60_00, 0x12)
+	mapper.WriteCHR(0x00_00, 0x34)
+
+	var state bytes.Buffer
+	assert.NoError(t, mapper.SaveState(&state))
+
+	restored := newMapperForTest(t, inesForTest(0, 1, 0))
+	assert.NoError(t, restored.LoadState(&state))
+
+	assert.Equal(t, byte(0x12), restored.ReadPRG(0x60_00))
+	assert.Equal(t, byte(0x34), restored.ReadCHR(0x00_00))
+}
+
+func Test_NROM_PRGRAMSizeFromNES2Header(t *testing.T) {
+	tests := []struct {
+		name    string
+		prgRAM  byte
+		mirrors bool
+	}{
+		// 64 << 5 = 2KB の PRG RAM は 0x6000-0x7FFF に 4 回繰り返す
+		{name: "2KB", prgRAM: 0x05, mirrors: true},
+		{name: "2KBBattery", prgRAM: 0x50, mirrors: true},
+		// NES 2.0 で大きさが 0 なら 8KB
+		{name: "None", prgRAM: 0x00, mirrors: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := inesForTest(0, 1, 1)
+			raw[7] |= 0b0000_1000
+			raw[10] = tt.prgRAM
+			mapper := newMapperForTest(t, raw)
+			mapper.WritePRG(0x60_00, 0x12)
+
+			assert.Equal(t, tt.mirrors, mapper.ReadPRG(0x68_00) == 0x12)
+			assert.Equal(t, tt.mirrors, mapper.ReadPRG(0x78_00) == 0x12)
+		})
+	}
+}
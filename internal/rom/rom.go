@@ -11,45 +11,127 @@ const (
 	Vertical Mirroring = iota
 	Horizontal
 	FourScreen
+	// SingleScreenA と SingleScreenB はマッパーが実行中に切り替える 1 画面ミラーリング。
+	// それぞれ VRAM の前半 1KB と後半 1KB だけを使う。
+	SingleScreenA
+	SingleScreenB
 )
 
+// Timing は CPU と PPU のタイミング (地域)。
+type Timing byte
+
+const (
+	// NTSC は RP2C02 の北米・日本向けの本体。
+	NTSC Timing = iota
+	// PAL は RP2C07 の欧州向けの本体。
+	PAL
+	// MultipleRegion はどの地域の本体でも動くことを表す。
+	MultipleRegion
+	// Dendy は UA6538 のロシアなどで使われた互換機。
+	Dendy
+)
+
+// ConsoleType は ROM が対象とする本体の種類。0x3 以降は NES 2.0 の拡張コンソールタイプの番号。
+//
+// https://www.nesdev.org/wiki/NES_2.0#Extended_Console_Type
+type ConsoleType byte
+
 const (
+	// Famicom は通常のファミコン (NES、Dendy)。
+	Famicom ConsoleType = iota
+	// VsSystem は業務用の Vs. System。
+	VsSystem
+	// Playchoice10 は業務用の PlayChoice-10。
+	Playchoice10
+	// DecimalFamiclone は 10 進モードを持つ CPU を載せた互換機。
+	DecimalFamiclone
+)
+
+const (
+	HeaderSize     = 16
 	PrgROMPageSize = 16_384
 	ChrROMPageSize = 8_192
 )
 
+// nes2Format はヘッダのバイト 7 の bit 2-3 が NES 2.0 の形式を表すときの値。
+const nes2Format = 0b10
+
 type ROM struct {
 	Prg             []byte
 	Chr             []byte
-	Mapper          byte
+	Mapper          uint16
 	ScreenMirroring Mirroring
+	// Battery はカートリッジがバッテリーでバックアップされたメモリを持つかどうか。
+	Battery bool
+
+	// 以降は NES 2.0 のヘッダにだけある情報。iNES ではゼロ値になる。
+
+	// NES2 はヘッダが NES 2.0 の形式かどうか。
+	NES2 bool
+	// Submapper はマッパーの変種を区別する番号。
+	Submapper byte
+	// PrgRAMSize と PrgNVRAMSize は PRG RAM と電池でバックアップされた PRG RAM のバイト数。
+	PrgRAMSize   int
+	PrgNVRAMSize int
+	// ChrRAMSize と ChrNVRAMSize は CHR RAM と電池でバックアップされた CHR RAM のバイト数。
+	ChrRAMSize   int
+	ChrNVRAMSize int
+	Timing       Timing
+	ConsoleType  ConsoleType
+	// ExpansionDevice は標準で接続する入力機器の番号。
+	//
+	// https://www.nesdev.org/wiki/NES_2.0#Default_Expansion_Device
+	ExpansionDevice byte
 }
 
+// NewROM は iNES か NES 2.0 の形式のファイルを読み込む。
+//
+// https://www.nesdev.org/wiki/INES
+// https://www.nesdev.org/wiki/NES_2.0
 func NewROM(raw []byte) (*ROM, error) {
+	if len(raw) < HeaderSize {
+		return nil, errors.New("file is too short for iNES header")
+	}
+
 	if !slices.Equal(raw[0:4], []byte{'N', 'E', 'S', 0x1A}) {
 		return nil, errors.New("file is not iNES file foramt")
 	}
 
-	inesVer := (raw[7] >> 2) & 0b0000_0011
-	if inesVer != 0x00 {
-		return nil, errors.New("NES2.0 format is not supported")
-	}
+	format := (raw[7] >> 2) & 0b0000_0011
+	nes2 := format == nes2Format
 
 	// [prg|chr]romsize
 	prgROMSize := uint(raw[4]) * PrgROMPageSize
 	chrROMSize := uint(raw[5]) * ChrROMPageSize
+	if nes2 {
+		prgROMSize = nes2ROMSize(raw[4], raw[9]&0x0F, PrgROMPageSize)
+		chrROMSize = nes2ROMSize(raw[5], raw[9]>>4, ChrROMPageSize)
+	}
 
 	skipTrainer := raw[6]&0b0000_0100 != 0
 
-	var prgROMStartPos uint = 16
+	var prgROMStartPos uint = HeaderSize
 	if skipTrainer {
 		prgROMStartPos += 512
 	}
 
 	chrROMStartPos := prgROMStartPos + prgROMSize
+	if uint(len(raw)) < chrROMStartPos+chrROMSize {
+		return nil, errors.New("file is smaller than PRG/CHR ROM size in header")
+	}
 
 	// mapper
-	mapper := (raw[7] & 0b1111_0000) | (raw[6] >> 4)
+	mapper := uint16(raw[7]&0b1111_0000) | uint16(raw[6]>>4)
+	if !nes2 && (format != 0b00 || !slices.Equal(raw[12:16], []byte{0, 0, 0, 0})) {
+		// 古いツールがバイト 7-15 に "DiskDude!" などを書き込んだ iNES ファイルでは、バイト 7 の上位 4 ビットは使えない
+		mapper &= 0x0F
+	}
+	if nes2 {
+		mapper |= uint16(raw[8]&0x0F) << 8
+	}
+	if _, ok := mappers[mapper]; !ok {
+		return nil, unsupportedMapperError(mapper)
+	}
 
 	// screenMirroring
 	isFourScreen := raw[6]&0b0000_1000 != 0
@@ -64,10 +146,58 @@ func NewROM(raw []byte) (*ROM, error) {
 		screenMirroring = Horizontal
 	}
 
-	return &ROM{
+	rom := &ROM{
 		Prg:             raw[prgROMStartPos : prgROMStartPos+prgROMSize],
 		Chr:             raw[chrROMStartPos : chrROMStartPos+chrROMSize],
 		Mapper:          mapper,
 		ScreenMirroring: screenMirroring,
-	}, nil
+		Battery:         raw[6]&0b0000_0010 != 0,
+	}
+	if nes2 {
+		rom.readNES2Header(raw)
+	}
+
+	return rom, nil
+}
+
+// readNES2Header は NES 2.0 のヘッダのバイト 7-15 にだけある情報を読み込む。
+func (rom *ROM) readNES2Header(raw []byte) {
+	rom.NES2 = true
+	rom.Submapper = raw[8] >> 4
+	rom.PrgRAMSize = nes2RAMSize(raw[10] & 0x0F)
+	rom.PrgNVRAMSize = nes2RAMSize(raw[10] >> 4)
+	rom.ChrRAMSize = nes2RAMSize(raw[11] & 0x0F)
+	rom.ChrNVRAMSize = nes2RAMSize(raw[11] >> 4)
+	rom.Timing = Timing(raw[12] & 0b0000_0011)
+
+	rom.ConsoleType = ConsoleType(raw[7] & 0b0000_0011)
+	// バイト 7 のコンソールタイプが 3 なら、本当の種類はバイト 13 の拡張コンソールタイプにある
+	if raw[7]&0b0000_0011 == 0b11 {
+		rom.ConsoleType = ConsoleType(raw[13] & 0x0F)
+	}
+
+	rom.ExpansionDevice = raw[15] & 0b0011_1111
+}
+
+// nes2ROMSize は NES 2.0 の ROM サイズの下位 8 ビット lsb と上位 4 ビット msb をバイト数にする。
+// msb が 0xF なら lsb は EEEEEEMM で、2^E * (MM*2+1) バイトを表す。
+func nes2ROMSize(lsb byte, msb byte, unit uint) uint {
+	if msb != 0x0F {
+		return (uint(msb)<<8 | uint(lsb)) * unit
+	}
+
+	// 大きすぎる値はどうせファイルに収まらないため、オーバーフローしないように抑える
+	exponent := min(lsb>>2, 40)
+	multiplier := uint(lsb&0b11)*2 + 1
+
+	return (1 << exponent) * multiplier
+}
+
+// nes2RAMSize は NES 2.0 の RAM サイズのシフト数をバイト数にする。0 なら RAM はない。
+func nes2RAMSize(shift byte) int {
+	if shift == 0 {
+		return 0
+	}
+
+	return 64 << shift
 }
@@ -1,8 +1,11 @@
 package rom
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNewRom(t *testing.T) {
@@ -21,18 +24,31 @@ func TestNewRom(t *testing.T) {
 				raw: []byte{
 					'N', 'E', 'S', 0x1A,
 					0x00, 0x00,
-					0b0110_0001, 0b1001_0000,
+					0b0000_0001, 0b0000_0000,
 					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 				},
 			},
 			want: &ROM{
 				Prg:             []byte{},
 				Chr:             []byte{},
-				Mapper:          0b1001_0110,
+				Mapper:          0,
 				ScreenMirroring: Vertical,
 			},
 			wantErr: false,
 		},
+		{
+			name: "Failure/Unsupported mapper",
+			args: args{
+				raw: []byte{
+					'N', 'E', 'S', 0x1A,
+					0x00, 0x00,
+					0b0110_0001, 0b1001_0000,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name: "Failure/Validate 'NES^Z'",
 			args: args{
@@ -44,12 +60,96 @@ func TestNewRom(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name: "Failure/Validate iNES Version",
+			name: "Success/Ignore garbage in iNES header",
+			args: args{
+				raw: []byte{
+					'N', 'E', 'S', 0x1A,
+					0x00, 0x00,
+					0b0001_0000, 'D',
+					'i', 's', 'k', 'D', 'u', 'd', 'e', '!',
+				},
+			},
+			want: &ROM{
+				Prg:             []byte{},
+				Chr:             []byte{},
+				Mapper:          1,
+				ScreenMirroring: Horizontal,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Success/NES 2.0",
+			args: args{
+				raw: append([]byte{
+					'N', 'E', 'S', 0x1A,
+					0x01, 0x00,
+					0b0100_0011, 0b0000_1001,
+					0b0001_0000, 0x00,
+					0x70, 0x07,
+					0x01, 0x00, 0x00, 0x01,
+				}, make([]byte, PrgROMPageSize)...),
+			},
+			want: &ROM{
+				Prg:             make([]byte, PrgROMPageSize),
+				Chr:             []byte{},
+				Mapper:          4,
+				ScreenMirroring: Vertical,
+				Battery:         true,
+				NES2:            true,
+				Submapper:       1,
+				PrgNVRAMSize:    8_192,
+				ChrRAMSize:      8_192,
+				Timing:          PAL,
+				ConsoleType:     VsSystem,
+				ExpansionDevice: 1,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Success/NES 2.0 exponent-multiplier ROM size and extended console type",
+			args: args{
+				raw: append([]byte{
+					'N', 'E', 'S', 0x1A,
+					// PRG ROM: 2^3 * (1*2+1) = 24 バイト
+					0b0000_1101, 0x00,
+					0b0000_0000, 0b0000_1011,
+					0x00, 0x0F,
+					0x00, 0x00,
+					0x03, 0x04, 0x00, 0x00,
+				}, make([]byte, 24)...),
+			},
+			want: &ROM{
+				Prg:             make([]byte, 24),
+				Chr:             []byte{},
+				Mapper:          0,
+				ScreenMirroring: Horizontal,
+				NES2:            true,
+				Timing:          Dendy,
+				ConsoleType:     4,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Failure/NES 2.0 unsupported 12-bit mapper",
 			args: args{
 				raw: []byte{
 					'N', 'E', 'S', 0x1A,
 					0x00, 0x00,
 					0b0000_0000, 0b0000_1000,
+					0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "Failure/Validate PRG ROM size",
+			args: args{
+				raw: []byte{
+					'N', 'E', 'S', 0x1A,
+					0x01, 0x00,
+					0b0000_0000, 0b0000_0000,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 				},
 			},
 			want:    nil,
@@ -69,3 +169,71 @@ func TestNewRom(t *testing.T) {
 		})
 	}
 }
+
+// smallNES2ForTest は NES 2.0 の指数表記で 8KB の PRG ROM と 4KB の CHR ROM を持つマッパー mapper のイメージを作る。
+// 各 ROM の 256 バイトごとの先頭からの番号をその範囲のすべてのバイトに書き込む。
+func smallNES2ForTest(mapper byte) []byte {
+	raw := []byte{
+		'N', 'E', 'S', 0x1A,
+		// 2^13 * 1 = 8KB, 2^12 * 1 = 4KB
+		13 << 2, 12 << 2,
+		mapper << 4, mapper&0b1111_0000 | 0b0000_1000,
+		0x00, 0xFF,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	for i := range 0x20_00 {
+		raw = append(raw, byte(i>>8))
+	}
+	for i := range 0x10_00 {
+		raw = append(raw, byte(i>>8))
+	}
+
+	return raw
+}
+
+func Test_NewROM_SmallROM(t *testing.T) {
+	tests := []struct {
+		mapper byte
+		setup  func(m Mapper)
+	}{
+		{mapper: 0},
+		{mapper: 1},
+		{mapper: 2},
+		{mapper: 3},
+		{
+			mapper: 4,
+			// CHR の 1KB のバンクを 0x0000 から順に 0-7 にする
+			setup: func(m Mapper) {
+				for register, bank := range []byte{0, 2, 4, 5, 6, 7} {
+					m.WritePRG(0x80_00, byte(register))
+					m.WritePRG(0x80_01, bank)
+				}
+			},
+		},
+		{mapper: 7},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("Mapper%d", tt.mapper), func(t *testing.T) {
+			rom, err := NewROM(smallNES2ForTest(tt.mapper))
+			assert.NoError(t, err)
+			assert.Len(t, rom.Prg, 0x20_00)
+			assert.Len(t, rom.Chr, 0x10_00)
+
+			m, err := NewMapper(rom)
+			assert.NoError(t, err)
+			if tt.setup != nil {
+				tt.setup(m)
+			}
+
+			// PRG ROM と CHR ROM はアドレス空間全体に繰り返し見える
+			for address := 0x80_00; address <= 0xFF_FF; address += 0x01_01 {
+				assert.Equal(t, byte(address&0x1F_FF>>8), m.ReadPRG(uint16(address)), "PRG $%04X", address)
+			}
+			assert.Equal(t, byte(0x1F), m.ReadPRG(0xFF_FF))
+			for address := 0x00_00; address <= 0x1F_FF; address += 0x00_81 {
+				assert.Equal(t, byte(address&0x0F_FF>>8), m.ReadCHR(uint16(address)), "CHR $%04X", address)
+			}
+			assert.Equal(t, byte(0x0F), m.ReadCHR(0x1F_FF))
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package rom
+
+import "io"
+
+// uxrom はマッパー 2。0x8000 の 16KB を切り替え、0xC000 は最後のバンクに固定する。CHR は 8KB の RAM か ROM。
+// 0x8000-0xFFFF への書き込みがバンク番号になり、バスコンフリクトが起きる。
+//
+// https://www.nesdev.org/wiki/UxROM
+type uxrom struct {
+	prg       []byte
+	chr       []byte
+	chrRAM    bool
+	mirroring Mirroring
+	bank      byte
+}
+
+func newUxROM(rom *ROM) Mapper {
+	chr, chrRAM := chrMemory(rom)
+
+	return &uxrom{
+		prg:       rom.Prg,
+		chr:       chr,
+		chrRAM:    chrRAM,
+		mirroring: rom.ScreenMirroring,
+	}
+}
+
+func (m *uxrom) ReadPRG(address uint16) byte {
+	if address < 0x80_00 || len(m.prg) == 0 {
+		return 0
+	}
+
+	bank := int(m.bank)
+	if address >= 0xC0_00 {
+		bank = max(len(m.prg)/PrgROMPageSize-1, 0)
+	}
+
+	return m.prg[wrapOffset(bank*PrgROMPageSize+int(address&0x3F_FF), len(m.prg))]
+}
+
+func (m *uxrom) WritePRG(address uint16, data byte) {
+	if address >= 0x80_00 {
+		m.bank = busConflict(m.ReadPRG(address), data)
+	}
+}
+
+func (m *uxrom) ReadCHR(address uint16) byte {
+	return m.chr[wrapOffset(int(address), len(m.chr))]
+}
+
+func (m *uxrom) WriteCHR(address uint16, data byte) {
+	if m.chrRAM {
+		m.chr[wrapOffset(int(address), len(m.chr))] = data
+	}
+}
+
+func (m *uxrom) Mirroring() Mirroring {
+	return m.mirroring
+}
+
+func (m *uxrom) IRQ() bool {
+	return false
+}
+
+func (m *uxrom) SaveState(w io.Writer) error {
+	if m.chrRAM {
+		return saveState(w, m.bank, m.chr)
+	}
+
+	return saveState(w, m.bank)
+}
+
+func (m *uxrom) LoadState(r io.Reader) error {
+	if m.chrRAM {
+		return loadState(r, &m.bank, m.chr)
+	}
+
+	return loadState(r, &m.bank)
+}
@@ -0,0 +1,50 @@
+package rom
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_UxROM_PRG(t *testing.T) {
+	tests := []struct {
+		name    string
+		rom     byte
+		data    byte
+		wantLow byte
+	}{
+		{name: "Switch", rom: 0xFF, data: 3, wantLow: 3},
+		{name: "Wrap", rom: 0xFF, data: 9, wantLow: 1},
+		// 書き込んだ値と ROM の値の AND がバンク番号になる
+		{name: "BusConflict", rom: 0b0110, data: 0b0011, wantLow: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := inesForTest(2, 8, 0)
+			setPRGForTest(raw, 7*PrgROMPageSize+0x10, tt.rom)
+			mapper := newMapperForTest(t, raw)
+			mapper.WritePRG(0xC0_10, tt.data)
+
+			assert.Equal(t, tt.wantLow, mapper.ReadPRG(0x80_00))
+			// 0xC000 は最後のバンクに固定されている
+			assert.Equal(t, byte(7), mapper.ReadPRG(0xC0_00))
+		})
+	}
+}
+
+func Test_UxROM_State(t *testing.T) {
+	raw := inesForTest(2, 8, 0)
+	setPRGForTest(raw, 7*PrgROMPageSize+0x10, 0xFF)
+	mapper := newMapperForTest(t, raw)
+	mapper.WritePRG(0xC0_10, 5)
+	mapper.WriteCHR(0x00_00, 0x34)
+
+	var state bytes.Buffer
+	assert.NoError(t, mapper.SaveState(&state))
+
+	restored := newMapperForTest(t, raw)
+	assert.NoError(t, restored.LoadState(&state))
+	assert.Equal(t, byte(5), restored.ReadPRG(0x80_00))
+	assert.Equal(t, byte(0x34), restored.ReadCHR(0x00_00))
+}